@@ -0,0 +1,87 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+)
+
+// действия пользователя с кубом (общие для мыши и клавиатуры)
+
+func (c *Cube) ChangeScreen(screen int) {
+	if !c.powerOn {
+		return
+	}
+	screen = (screen%len(c.screens) + len(c.screens)) % len(c.screens)
+	data, _ := json.Marshal(CubeInfo{Type: TYPE_CHANGE, Screen: &screen})
+	c.SendToServer(string(data))
+}
+
+func (c *Cube) Left() {
+	c.ChangeScreen(c.active - 1)
+}
+
+func (c *Cube) Right() {
+	c.ChangeScreen(c.active + 1)
+}
+
+// SelectItem выбирает элемент списка на активном экране
+func (c *Cube) SelectItem(pos int) {
+	if !c.descriptors[c.active].navigable {
+		return
+	}
+	data, _ := json.Marshal(CubeInfo{Type: TYPE_CHANGE, Screen: &c.active, State: &pos})
+	c.SendToServer(string(data))
+}
+
+// Up - короткое нажатие перемещает выбор вверх, длинное - к началу списка
+func (c *Cube) Up(long bool) {
+	if long {
+		c.SelectItem(0)
+		return
+	}
+	pos := c.descriptors[c.active].selected - 1
+	if pos < 0 {
+		pos = c.descriptors[c.active].count - 1
+	}
+	c.SelectItem(pos)
+}
+
+// Down - короткое нажатие перемещает выбор вниз, длинное - к концу списка
+func (c *Cube) Down(long bool) {
+	if long {
+		c.SelectItem(c.descriptors[c.active].count - 1)
+		return
+	}
+	pos := c.descriptors[c.active].selected + 1
+	if pos >= c.descriptors[c.active].count {
+		pos = 0
+	}
+	c.SelectItem(pos)
+}
+
+func (c *Cube) Tap(long bool) {
+	tap := CubeInfo{Type: TYPE_TAP, Screen: &c.active}
+	if long {
+		tap.Type = TYPE_LONGTAP
+	}
+	list := c.screenLists[c.active]
+	//в пустом списке выбирать нечего, нажатие уходит без номера
+	if selected := c.descriptors[c.active].selected; c.descriptors[c.active].navigable && selected < len(list) {
+		tap.State = &list[selected].Number
+	}
+	data, _ := json.Marshal(tap)
+	c.SendToServer(string(data))
+}
+
+func (c *Cube) Menu() {
+	data, _ := json.Marshal(CubeInfo{Type: TYPE_MENU})
+	c.SendToServer(string(data))
+}
+
+func (c *Cube) TogglePower() {
+	c.powerOn = !c.powerOn
+	c.StoreConfig()
+	c.UpdatePowerState()
+}
@@ -0,0 +1,218 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const ANIMATION_RGB565 = "rgb565"
+const ANIMATION_PNG = "png"
+
+const defaultFrameDuration = 100 //ms
+
+type AnimationFrame struct {
+	Content  *string `json:"content" example:"SGVsbG8="`
+	Duration int     `json:"duration" example:"100"`
+}
+
+// AnimationDescriptor описывает анимацию экрана: либо список кадров, либо спрайт-лист,
+// в котором кадры размером с экран идут друг за другом (для png - слева направо)
+type AnimationDescriptor struct {
+	Format string           `json:"format" example:"rgb565"`
+	Sheet  *string          `json:"sheet" example:"SGVsbG8="`
+	Frames []AnimationFrame `json:"frames"`
+	Loop   int              `json:"loop" example:"0"`                  //0 - бесконечно
+	Scan   string           `json:"scan" example:"columns/180/mirror"` //порядок пикселей rgb565, по умолчанию - профиля
+}
+
+type AnimationPlayer struct {
+	cube      *Cube
+	screen    int
+	frames    [][]byte
+	durations []time.Duration
+	loop      int
+	current   int
+	stop      chan bool
+}
+
+// DecodePNGFrames нарезает png на кадры размером с экран слева направо
+func DecodePNGFrames(data []byte, count int) ([][]byte, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := img.Bounds()
+	if count <= 0 {
+		count = bounds.Dx() / screenWidth
+	}
+	frames := make([][]byte, 0, count)
+	for f := 0; f < count; f++ {
+		frame := make([]byte, screenWidth*screenHeight*4)
+		for y := 0; y < screenHeight; y++ {
+			for x := 0; x < screenWidth; x++ {
+				p := image.Pt(bounds.Min.X+f*screenWidth+x, bounds.Min.Y+y)
+				pos := (y*screenWidth + x) * 4
+				frame[pos+3] = 255
+				if !p.In(bounds) {
+					continue
+				}
+				r, g, b, _ := img.At(p.X, p.Y).RGBA()
+				frame[pos] = byte(r >> 8)
+				frame[pos+1] = byte(g >> 8)
+				frame[pos+2] = byte(b >> 8)
+			}
+		}
+		frames = append(frames, frame)
+	}
+	return frames, nil
+}
+
+func DecodeAnimation(descriptor AnimationDescriptor) ([][]byte, error) {
+	var frames [][]byte
+	mode := ParseScanMode(descriptor.Scan, profile.Scan)
+	if descriptor.Sheet != nil {
+		sheet, err := base64.StdEncoding.DecodeString(*descriptor.Sheet)
+		if err != nil {
+			return nil, err
+		}
+		if descriptor.Format == ANIMATION_PNG {
+			return DecodePNGFrames(sheet, len(descriptor.Frames))
+		}
+		frameSize := screenWidth * screenHeight * profile.BytesPerPixel()
+		for pos := 0; pos+frameSize <= len(sheet); pos += frameSize {
+			frames = append(frames, DecodeScreenImage(sheet[pos:pos+frameSize], mode))
+		}
+		return frames, nil
+	}
+	for _, f := range descriptor.Frames {
+		if f.Content == nil {
+			continue
+		}
+		content, err := base64.StdEncoding.DecodeString(*f.Content)
+		if err != nil {
+			return nil, err
+		}
+		if descriptor.Format == ANIMATION_PNG {
+			decoded, err := DecodePNGFrames(content, 1)
+			if err != nil {
+				return nil, err
+			}
+			frames = append(frames, decoded[0])
+		} else {
+			frames = append(frames, DecodeScreenImage(content, mode))
+		}
+	}
+	return frames, nil
+}
+
+// DrawFrame выводит кадр на экран с учетом переворота куба
+func (c *Cube) DrawFrame(screen int, frame []byte) {
+	if !c.powerOn {
+		return
+	}
+	for y := 0; y < screenHeight; y++ {
+		for x := 0; x < screenWidth; x++ {
+			pos := (y*screenWidth + x) * 4
+			c.SetPixel(screen, x, y, frame[pos], frame[pos+1], frame[pos+2])
+		}
+	}
+}
+
+func (c *Cube) PlayAnimation(screen int, descriptor AnimationDescriptor) {
+	frames, err := DecodeAnimation(descriptor)
+	if err != nil || len(frames) == 0 {
+		println("Animation can't be decoded ", screen)
+		return
+	}
+	player := &AnimationPlayer{
+		cube:   c,
+		screen: screen,
+		frames: frames,
+		loop:   descriptor.Loop,
+		stop:   make(chan bool, 1),
+	}
+	for i := range frames {
+		ms := defaultFrameDuration
+		if i < len(descriptor.Frames) && descriptor.Frames[i].Duration > 0 {
+			ms = descriptor.Frames[i].Duration
+		}
+		player.durations = append(player.durations, time.Duration(ms)*time.Millisecond)
+	}
+	c.StopAnimation(screen)
+	c.animations[screen] = player
+	c.descriptors[screen].list = false
+	c.descriptors[screen].navigable = false
+	go player.run()
+}
+
+func (p *AnimationPlayer) run() {
+	for iteration := 0; p.loop == 0 || iteration < p.loop; iteration++ {
+		for frame := 0; frame < len(p.frames); frame++ {
+			stopped := false
+			Update(func() {
+				//пока ждали блокировку, анимацию могли остановить и вывести на экран другое
+				if p.cube.animations[p.screen] != p {
+					stopped = true
+					return
+				}
+				p.current = frame
+				p.cube.DrawFrame(p.screen, p.frames[frame])
+			})
+			if stopped {
+				return
+			}
+			select {
+			case <-p.stop:
+				return
+			case <-time.After(p.durations[frame]):
+			}
+		}
+	}
+}
+
+// Redraw перерисовывает текущий кадр (например, после переворота куба)
+func (p *AnimationPlayer) Redraw() {
+	if p.current < len(p.frames) {
+		p.cube.DrawFrame(p.screen, p.frames[p.current])
+	}
+}
+
+func (c *Cube) StopAnimation(screen int) {
+	if c.animations[screen] != nil {
+		c.animations[screen].stop <- true
+		c.animations[screen] = nil
+	}
+}
+
+func (c *Cube) StopAnimations() {
+	for i := range c.animations {
+		c.StopAnimation(i)
+	}
+}
+
+func (c *Cube) GetAnimationFromNetwork(screen int) {
+	go c.FetchScreen(screen, "/animation/"+strconv.Itoa(screen), "", func(resp *FetchResponse) {
+		if resp.Status != http.StatusOK {
+			return
+		}
+		var descriptor AnimationDescriptor
+		if err := json.Unmarshal(resp.Body, &descriptor); err != nil {
+			println("Animation is broken ", screen)
+			c.ReportError(screen, err)
+			return
+		}
+		//анимация не сохраняется, после перезапуска не показываем устаревшее изображение
+		c.DropCachedScreen(screen)
+		c.StartTransition(screen)
+		c.PlayAnimation(screen, descriptor)
+	})
+}
@@ -0,0 +1,114 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Последнее полученное содержимое экранов хранится в настройках и выводится сразу при включении,
+// как это делает настоящий куб; затем куб проверяет его условным запросом.
+// Сервер должен открыть ETag и Last-Modified через Access-Control-Expose-Headers.
+
+const CACHE_IMAGE = "image"
+const CACHE_LIST = "list"
+
+type CachedScreen struct {
+	Kind         string `json:"kind"`
+	Data         []byte `json:"data"`
+	Scan         string `json:"scan,omitempty"` //порядок пикселей изображения
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+func (c *Cube) cacheKey(screen int) string {
+	return c.Key("cache." + strconv.Itoa(screen))
+}
+
+func (c *Cube) CachedScreen(screen int) *CachedScreen {
+	data := storage.Get(c.cacheKey(screen))
+	if data == nil {
+		return nil
+	}
+	var cached CachedScreen
+	if err := json.Unmarshal([]byte(*data), &cached); err != nil {
+		println("Cached screen is broken ", screen)
+		return nil
+	}
+	return &cached
+}
+
+func (c *Cube) StoreCachedScreen(screen int, kind string, content []byte, header http.Header, mode ScanMode) {
+	cached := CachedScreen{
+		Kind:         kind,
+		Data:         content,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+	if kind == CACHE_IMAGE {
+		cached.Scan = mode.String()
+	}
+	data, _ := json.Marshal(cached)
+	if err := storage.Set(c.cacheKey(screen), &data); err != nil {
+		//в хранилище не осталось места; старое содержимое с его ETag оставлять нельзя
+		println("Can't cache screen ", screen, err.Error())
+		c.DropCachedScreen(screen)
+	}
+}
+
+func (c *Cube) DropCachedScreen(screen int) {
+	storage.Set(c.cacheKey(screen), nil)
+}
+
+func (c *Cube) ClearCache() {
+	for i := range c.screens {
+		c.DropCachedScreen(i)
+	}
+}
+
+// Conditional добавляет к запросу If-None-Match и If-Modified-Since, если есть сохраненное содержимое того же вида
+func (c *Cube) Conditional(header http.Header, screen int, kind string) {
+	cached := c.CachedScreen(screen)
+	if cached == nil || cached.Kind != kind {
+		return
+	}
+	if cached.ETag != "" {
+		header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		header.Set("If-Modified-Since", cached.LastModified)
+	}
+}
+
+func (c *Cube) ShowCachedScreen(screen int, cached *CachedScreen) {
+	switch cached.Kind {
+	case CACHE_IMAGE:
+		c.ShowImage(screen, cached.Data, ParseScanMode(cached.Scan, profile.Scan))
+	case CACHE_LIST:
+		var result ListDescriptor
+		if err := json.Unmarshal(cached.Data, &result); err != nil {
+			println("Cached list is broken ", screen)
+			return
+		}
+		c.ShowList(screen, result)
+	}
+}
+
+// RestoreCache выводит сохраненное содержимое экранов и запрашивает обновления
+func (c *Cube) RestoreCache() {
+	for i := range c.screens {
+		cached := c.CachedScreen(i)
+		if cached == nil {
+			continue
+		}
+		c.ShowCachedScreen(i, cached)
+		if cached.Kind == CACHE_LIST {
+			c.GetListFromNetwork(i)
+		} else {
+			c.GetImageFromNetwork(i)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"github.com/llgcode/draw2d/draw2dimg"
+	"image"
+	"syscall/js"
+)
+
+type RenderFunc func(gc *draw2dimg.GraphicContext) bool
+
+// ScreenCanvas выводит экран на canvas на каждом кадре браузера (requestAnimationFrame).
+// Свой цикл вместо go-canvas: его Canvas2d.Stop зависает на неинициализированном канале.
+type ScreenCanvas struct {
+	canvas  js.Value
+	ctx     js.Value
+	imgData js.Value
+	buffer  js.Value //Uint8Array для копирования кадра в JS
+	image   *image.RGBA
+	gc      *draw2dimg.GraphicContext
+	render  RenderFunc
+
+	frame   js.Func
+	request js.Value
+	stopped bool
+}
+
+func NewScreenCanvas(canvas js.Value, width int, height int, render RenderFunc) *ScreenCanvas {
+	s := &ScreenCanvas{canvas: canvas, ctx: canvas.Call("getContext", "2d"), render: render}
+	s.setSize(width, height)
+	return s
+}
+
+func (s *ScreenCanvas) setSize(width int, height int) {
+	s.imgData = s.ctx.Call("createImageData", width, height)
+	s.image = image.NewRGBA(image.Rect(0, 0, width, height))
+	s.buffer = js.Global().Get("Uint8Array").New(len(s.image.Pix))
+	s.gc = draw2dimg.NewGraphicContext(s.image)
+}
+
+// Resize меняет размер кадра и способ отрисовки, не останавливая вывод
+func (s *ScreenCanvas) Resize(width int, height int, render RenderFunc) {
+	s.setSize(width, height)
+	s.render = render
+}
+
+// Start запускает вывод кадров
+func (s *ScreenCanvas) Start() {
+	s.frame = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		//render сам берет блокировку, поэтому остановку проверяем до и после него
+		stopped := false
+		var current ScreenCanvas
+		Update(func() {
+			stopped = s.stopped
+			current = *s
+		})
+		if stopped {
+			return nil
+		}
+		if current.render(current.gc) {
+			js.CopyBytesToJS(current.buffer, current.image.Pix)
+			current.imgData.Get("data").Call("set", current.buffer)
+			current.ctx.Call("putImageData", current.imgData, 0, 0)
+		}
+		Update(func() {
+			if !s.stopped {
+				s.request = js.Global().Call("requestAnimationFrame", s.frame)
+			}
+		})
+		return nil
+	})
+	s.request = js.Global().Call("requestAnimationFrame", s.frame)
+}
+
+// Stop останавливает вывод кадров; кадр, который уже выводится, следующий не запрашивает
+func (s *ScreenCanvas) Stop() {
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	js.Global().Call("cancelAnimationFrame", s.request)
+	s.frame.Release()
+}
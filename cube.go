@@ -0,0 +1,264 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/go-playground/colors"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"github.com/hexops/vecty/prop"
+	"image/color"
+	"strconv"
+	"syscall/js"
+	"time"
+)
+
+// Cube хранит состояние одного эмулируемого куба
+type Cube struct {
+	id int
+	ScreenState
+	cvs         []*ScreenCanvas
+	descriptors []ScreenDescriptor
+	screenLists [][]ListItem
+	animations  []*AnimationPlayer
+	fetchErrors []string
+	active      int
+
+	powerOn     bool
+	lightColor  colors.Color
+	orientation Orientation
+	gestures    map[string]*GestureRecognizer
+	swipeX      float64
+	swiping     bool
+
+	socketConnected   bool
+	ws                js.Value
+	token             *string
+	sn                *uint32
+	registration_mode bool
+	reg               Registration
+	pairingQR         bool //показывать при привязке QR-код вместо PIN
+	backend           string
+	identity          string //имя сохраненной учетной записи устройства, "" - не сохранена
+
+	lightEffect      *LightEffect
+	lightEffectStart time.Time
+	baseLightColor   colors.Color
+	lightTick        js.Func
+	lightInterval    *js.Value
+
+	view3D bool
+
+	shake         ShakeModel
+	shakeDetector ShakeDetector
+	motion        bool
+
+	display DisplaySettings
+	lcdLUT  [256]byte
+
+	snapshotScale int
+	recorder      *Recorder
+	recordFace    int
+
+	buzzer       Buzzer
+	muted        bool
+	soundPlaying bool
+	soundEnd     time.Time
+}
+
+var cubes []*Cube
+
+func NewCube(id int) *Cube {
+	c := &Cube{id: id, ScreenState: NewScreenState(faceCount), orientation: IdentityOrientation(), snapshotScale: 1, recordFace: RECORD_ALL_FACES}
+	for i := 0; i < faceCount; i++ {
+		descriptor := ScreenDescriptor{navigable: false, topY: 0, topLine: 0, selected: 0}
+		c.descriptors = append(c.descriptors, descriptor)
+		items := make([]ListItem, 0, 0)
+		c.screenLists = append(c.screenLists, items)
+		c.animations = append(c.animations, nil)
+		c.fetchErrors = append(c.fetchErrors, "")
+		c.cvs = append(c.cvs, nil)
+	}
+	c.lightColor = colors.FromStdColor(color.Black)
+	c.LoadConfig()
+	c.InitBuzzer()
+	c.LoadShakeModel()
+	c.LoadDisplaySettings()
+	view3D := storage.Get(c.Key("view3d"))
+	c.view3D = view3D != nil && *view3D == "true"
+	pairing := storage.Get(c.Key("pairing"))
+	c.pairingQR = pairing != nil && *pairing == "qr"
+	return c
+}
+
+// Key возвращает ключ хранилища для куба (первый куб использует ключи без префикса)
+func (c *Cube) Key(key string) string {
+	if c.id == 0 {
+		return key
+	}
+	return "cube" + strconv.Itoa(c.id) + "." + key
+}
+
+func (c *Cube) LoadConfig() {
+	c.token = nil
+	c.sn = nil
+	c.powerOn = false
+	c.backend = DefaultBackend
+	if identity := storage.Get(c.Key("identity")); identity != nil {
+		c.identity = *identity
+	}
+	conf := storage.Get(c.Key("config"))
+	if conf == nil {
+		//goto registration mode
+		c.registration_mode = true
+		return
+	}
+	var config Configuration
+	err := json.Unmarshal([]byte(*conf), &config)
+	if err == nil && config.Backend != "" {
+		c.backend = config.Backend
+	}
+	if err != nil || config.Token == "" {
+		//goto registration mode
+		c.registration_mode = true
+		return
+	}
+	c.token = &config.Token
+	c.sn = &config.SN
+	c.registration_mode = false
+	if config.PoweredOn != nil {
+		c.powerOn = *config.PoweredOn
+	}
+}
+
+func (c *Cube) CanvasID(screen int) string {
+	return "canvas" + strconv.Itoa(c.id) + "-" + strconv.Itoa(screen)
+}
+
+// Element ищет элемент внутри панели куба
+func (c *Cube) Element(selector string) js.Value {
+	return js.Global().Get("document").Call("querySelector", "#cube"+strconv.Itoa(c.id)+" "+selector)
+}
+
+func (c *Cube) AttachCanvas(screen int) {
+	d := js.Global().Get("document").Call("getElementById", c.CanvasID(screen))
+	cv := NewScreenCanvas(d, screenWidth*c.CanvasScale(), screenHeight*c.CanvasScale(), c.MakeRenderCanvas(screen))
+	cv.Start()
+	c.cvs[screen] = cv
+}
+
+// ResizeCanvases подгоняет кадры canvas под новый масштаб экранов; сам canvas и его цикл остаются
+func (c *Cube) ResizeCanvases() {
+	for i, cv := range c.cvs {
+		if cv != nil {
+			cv.Resize(screenWidth*c.CanvasScale(), screenHeight*c.CanvasScale(), c.MakeRenderCanvas(i))
+		}
+	}
+}
+
+func (c *Cube) DetachCanvas(screen int) {
+	if c.cvs[screen] != nil {
+		c.cvs[screen].Stop()
+		c.cvs[screen] = nil
+	}
+}
+
+type CubeView struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *CubeView) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	return elem.Div(
+		vecty.Markup(
+			prop.ID("cube"+strconv.Itoa(c.id)),
+			vecty.Class("cube"),
+			vecty.MarkupIf(len(cubes) > 1 && focusedCube == c.id, vecty.Class("focused")),
+			On("pointerdown", func(event *vecty.Event) {
+				c.Focus()
+			}),
+		),
+		&PowerOnButton{cube: c},
+		&RegistrationPanel{cube: c},
+		&ButtonPanel{cube: c},
+		&Screens{cube: c},
+		&BottomLight{cube: c},
+		&FlipButton{cube: c},
+		&TiltPanel{cube: c},
+		elem.Div(vecty.Markup(vecty.Class("centered", "tools")),
+			&SoundIndicator{cube: c},
+			&View3DButton{cube: c},
+			&ShakeControl{cube: c},
+			&SnapshotPanel{cube: c},
+			&RecorderPanel{cube: c},
+			&DisplayPanel{cube: c},
+			&ResetPanel{cube: c},
+		),
+		&IdentityPanel{cube: c},
+		&NetworkStatus{cube: c},
+	)
+}
+
+func AddCube() {
+	c := NewCube(len(cubes))
+	cubes = append(cubes, c)
+	StoreCubesCount()
+	vecty.Rerender(emulator)
+	c.UpdatePowerState()
+}
+
+// RemoveCube выключает последний куб; его настройки остаются в хранилище
+func RemoveCube() {
+	if len(cubes) <= 1 {
+		return
+	}
+	c := cubes[len(cubes)-1]
+	if c.powerOn {
+		c.powerOn = false
+		c.UpdatePowerState()
+	}
+	//запись и эффект подсветки не зависят от питания, их горутина и таймер иначе переживут куб
+	c.StopRecording()
+	c.StopLightEffect()
+	if !c.lightTick.IsUndefined() {
+		c.lightTick.Release()
+		c.lightTick = js.Func{}
+	}
+	cubes = cubes[:len(cubes)-1]
+	if focusedCube >= len(cubes) {
+		focusedCube = len(cubes) - 1
+	}
+	StoreCubesCount()
+	vecty.Rerender(emulator)
+}
+
+func StoreCubesCount() {
+	count := []byte(strconv.Itoa(len(cubes)))
+	storage.Set("cubes", &count)
+}
+
+type CubesToolbar struct {
+	vecty.Core
+}
+
+func (p *CubesToolbar) Render() vecty.ComponentOrHTML {
+	return elem.Div(vecty.Markup(vecty.Class("centered", "cubes-toolbar")),
+		elem.Span(vecty.Markup(
+			vecty.Class("fa-button"),
+			vecty.MarkupIf(len(cubes) <= 1, vecty.Class("disabled")),
+			On("click", func(event *vecty.Event) {
+				RemoveCube()
+			}),
+		), vecty.Text("\uF056")),
+		elem.Span(vecty.Markup(
+			vecty.Class("fa-button"),
+			On("click", func(event *vecty.Event) {
+				AddCube()
+			}),
+		), vecty.Text("\uF055")),
+		&StoragePanel{},
+	)
+}
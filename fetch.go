@@ -0,0 +1,157 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// общий клиент для запросов содержимого экранов и загрузки шрифта
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+const fetchRetries = 3
+const fetchBackoff = 500 * time.Millisecond //удваивается с каждой попыткой
+
+type FetchResponse struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+func (c *Cube) CancelRequest(screen int) {
+	c.requests.Cancel(screen)
+}
+
+func (c *Cube) CancelRequests() {
+	c.requests.CancelAll()
+}
+
+// FetchScreen запрашивает содержимое экрана с повторами при ошибках сети и сервера и вызывает apply
+// под блокировкой состояния с ответом 200 или 304. apply не вызывается, если запрос отменен,
+// заменен более новым или не удался (ошибка показывается под кубом).
+// kind включает условный запрос по сохраненному содержимому. Вызывается из горутины.
+func (c *Cube) FetchScreen(screen int, path string, kind string, apply func(resp *FetchResponse)) {
+	var ctx context.Context
+	var seq int
+	var url string
+	header := http.Header{}
+	paired := true
+	Update(func() {
+		ctx, seq = c.requests.Begin(screen)
+		if c.token == nil {
+			paired = false
+			c.ReportError(screen, errors.New("device is not paired"))
+			return
+		}
+		url = c.APIURL() + path
+		header.Set("Authorization", "bearer "+*c.token)
+		if kind != "" {
+			c.Conditional(header, screen, kind)
+		}
+	})
+	if !paired {
+		return
+	}
+	var resp *FetchResponse
+	var err error
+	for attempt := 0; ; attempt++ {
+		resp, err = fetch(ctx, url, header)
+		if err == nil && resp.Status < 500 && resp.Status != http.StatusTooManyRequests {
+			break
+		}
+		if attempt == fetchRetries || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(fetchBackoff << uint(attempt)):
+		}
+	}
+	Update(func() {
+		if !c.requests.Finish(screen, seq) {
+			//пока ждали ответа, пришло более новое обновление экрана
+			return
+		}
+		if err != nil {
+			c.ReportError(screen, err)
+			return
+		}
+		switch resp.Status {
+		case http.StatusOK, http.StatusNotModified:
+			c.ReportError(screen, nil)
+			apply(resp)
+		case http.StatusUnauthorized:
+			c.TokenRevoked()
+		case http.StatusNotFound:
+			//для экрана нет содержимого
+			c.ReportError(screen, nil)
+		default:
+			c.ReportError(screen, errors.New("HTTP "+strconv.Itoa(resp.Status)))
+		}
+	})
+}
+
+func fetch(ctx context.Context, url string, header http.Header) (*FetchResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &FetchResponse{Status: resp.StatusCode, Header: resp.Header, Body: body}, nil
+}
+
+// ReportError показывает под кубом последнюю ошибку загрузки экрана; nil убирает ее
+func (c *Cube) ReportError(screen int, err error) {
+	message := ""
+	if err != nil {
+		message = err.Error()
+		println("Screen ", screen, " failed: ", message)
+	}
+	if c.fetchErrors[screen] != message {
+		c.fetchErrors[screen] = message
+		vecty.Rerender(emulator)
+	}
+}
+
+func (c *Cube) ClearErrors() {
+	for i := range c.fetchErrors {
+		c.fetchErrors[i] = ""
+	}
+}
+
+type NetworkStatus struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *NetworkStatus) Render() vecty.ComponentOrHTML {
+	var lines vecty.List
+	for i, message := range p.cube.fetchErrors {
+		if message != "" {
+			lines = append(lines, elem.Div(
+				elem.Span(vecty.Markup(vecty.Class("network-error-icon")), vecty.Text("\uF071")),
+				vecty.Text(" face "+strconv.Itoa(i+1)+": "+message),
+			))
+		}
+	}
+	return elem.Div(vecty.Markup(vecty.Class("network-errors")), lines)
+}
@@ -0,0 +1,148 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"strconv"
+	"syscall/js"
+	"time"
+)
+
+// кнопки стандартной раскладки Gamepad API
+const PAD_A = 0
+const PAD_B = 1
+const PAD_X = 2
+const PAD_LB = 4
+const PAD_RB = 5
+const PAD_SELECT = 8
+const PAD_START = 9
+const PAD_UP = 12
+const PAD_DOWN = 13
+const PAD_LEFT = 14
+const PAD_RIGHT = 15
+
+const gamepadPollInterval = 16 * time.Millisecond
+
+// PadBinding - действие кнопки; level - число пройденных порогов удержания
+type PadBinding struct {
+	thresholds func() []int
+	release    func(c *Cube, level int)
+}
+
+func noThresholds() []int {
+	return nil
+}
+
+var padBindings = map[int]PadBinding{
+	PAD_UP:    {JumpThresholds, func(c *Cube, level int) { c.Up(level > 0) }},
+	PAD_DOWN:  {JumpThresholds, func(c *Cube, level int) { c.Down(level > 0) }},
+	PAD_LEFT:  {noThresholds, func(c *Cube, level int) { c.Left() }},
+	PAD_RIGHT: {noThresholds, func(c *Cube, level int) { c.Right() }},
+	PAD_LB:    {noThresholds, func(c *Cube, level int) { c.Left() }},
+	PAD_RB:    {noThresholds, func(c *Cube, level int) { c.Right() }},
+	PAD_A: {TapThresholds, func(c *Cube, level int) {
+		if level < 2 {
+			c.Tap(level == 1)
+		} else {
+			c.Menu()
+		}
+	}},
+	PAD_B: {FlipThresholds, func(c *Cube, level int) {
+		if level == 0 {
+			c.Flip()
+		} else {
+			c.Shake()
+		}
+	}},
+	PAD_X:      {noThresholds, func(c *Cube, level int) { c.Shake() }},
+	PAD_START:  {noThresholds, func(c *Cube, level int) { c.Menu() }},
+	PAD_SELECT: {noThresholds, func(c *Cube, level int) { c.TogglePower() }},
+}
+
+var gamepadPolling bool
+
+// InitGamepads начинает опрос геймпадов после подключения первого из них;
+// опрос останавливается, когда отключены все геймпады
+func InitGamepads() {
+	if !js.Global().Get("navigator").Get("getGamepads").Truthy() {
+		return
+	}
+	js.Global().Call("addEventListener", "gamepadconnected", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		println("Gamepad connected ", args[0].Get("gamepad").Get("id").String())
+		Update(func() {
+			if !gamepadPolling {
+				gamepadPolling = true
+				go PollGamepads()
+			}
+		})
+		return nil
+	}))
+}
+
+// GamepadCube - куб, которым управляет геймпад: n-й геймпад - n-й куб, иначе куб в фокусе
+func GamepadCube(index int) *Cube {
+	if len(cubes) > 1 && index < len(cubes) {
+		return cubes[index]
+	}
+	if focusedCube < len(cubes) {
+		return cubes[focusedCube]
+	}
+	return nil
+}
+
+func PollGamepads() {
+	pressed := make(map[int]map[int]bool)
+	for {
+		stop := false
+		Update(func() {
+			connected := 0
+			pads := js.Global().Get("navigator").Call("getGamepads")
+			for i := 0; i < pads.Length(); i++ {
+				pad := pads.Index(i)
+				if !pad.Truthy() || !pad.Get("connected").Bool() {
+					continue
+				}
+				connected++
+				index := pad.Get("index").Int()
+				c := GamepadCube(index)
+				if c == nil {
+					continue
+				}
+				if pressed[index] == nil {
+					pressed[index] = make(map[int]bool)
+				}
+				buttons := pad.Get("buttons")
+				for button, binding := range padBindings {
+					if button >= buttons.Length() {
+						continue
+					}
+					down := buttons.Index(button).Get("pressed").Bool()
+					if down == pressed[index][button] {
+						continue
+					}
+					pressed[index][button] = down
+					g := c.Gesture("pad" + strconv.Itoa(index) + "-" + strconv.Itoa(button))
+					if down {
+						g.Begin(binding.thresholds(), time.Now())
+						continue
+					}
+					level, ok := g.End(time.Now())
+					if ok && (c.powerOn || button == PAD_SELECT) {
+						binding.release(c, level)
+					}
+				}
+			}
+			if connected == 0 {
+				//опрос возобновится при подключении следующего геймпада
+				println("All gamepads disconnected")
+				gamepadPolling = false
+				stop = true
+			}
+		})
+		if stop {
+			return
+		}
+		time.Sleep(gamepadPollInterval)
+	}
+}
@@ -0,0 +1,117 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// GestureTimings - пороги удержания кнопок, ms
+type GestureTimings struct {
+	Flip    int `json:"flip"`     //короче - переворот, дольше - встряхивание
+	LongTap int `json:"long_tap"` //короче - касание, дольше - долгое касание
+	Menu    int `json:"menu"`     //дольше - меню
+	Jump    int `json:"jump"`     //дольше - переход в начало/конец списка
+}
+
+func DefaultGestureTimings() GestureTimings {
+	return GestureTimings{Flip: 500, LongTap: 1000, Menu: 2000, Jump: 1000}
+}
+
+var gestureTimings = DefaultGestureTimings()
+
+// LoadGestureTimings читает пороги из настроек ("gestures"); отсутствующие поля остаются по умолчанию
+func LoadGestureTimings() {
+	gestureTimings = DefaultGestureTimings()
+	stored := storage.Get("gestures")
+	if stored != nil {
+		if err := json.Unmarshal([]byte(*stored), &gestureTimings); err != nil {
+			println("Gesture timings are broken")
+			gestureTimings = DefaultGestureTimings()
+		}
+	}
+}
+
+func FlipThresholds() []int {
+	return []int{gestureTimings.Flip}
+}
+
+func TapThresholds() []int {
+	return []int{gestureTimings.LongTap, gestureTimings.Menu}
+}
+
+func JumpThresholds() []int {
+	return []int{gestureTimings.Jump}
+}
+
+// GestureRecognizer определяет жест по времени удержания: уровень - число пройденных порогов
+type GestureRecognizer struct {
+	thresholds []int
+	start      time.Time
+	pressed    bool
+}
+
+func (g *GestureRecognizer) Begin(thresholds []int, t time.Time) {
+	g.thresholds = thresholds
+	g.start = t
+	g.pressed = true
+}
+
+func (g *GestureRecognizer) Pressed() bool {
+	return g.pressed
+}
+
+func (g *GestureRecognizer) Level(t time.Time) int {
+	ms := int(t.Sub(g.start).Milliseconds())
+	level := 0
+	for _, threshold := range g.thresholds {
+		if ms >= threshold {
+			level++
+		}
+	}
+	return level
+}
+
+// Progress - доля времени до последнего порога (0..1)
+func (g *GestureRecognizer) Progress(t time.Time) float64 {
+	if len(g.thresholds) == 0 {
+		return 1
+	}
+	last := g.thresholds[len(g.thresholds)-1]
+	if last <= 0 {
+		return 1
+	}
+	p := float64(t.Sub(g.start).Milliseconds()) / float64(last)
+	if p > 1 {
+		p = 1
+	}
+	return p
+}
+
+// End завершает жест; ok = false, если кнопка не была нажата
+func (g *GestureRecognizer) End(t time.Time) (level int, ok bool) {
+	if !g.pressed {
+		return 0, false
+	}
+	g.pressed = false
+	return g.Level(t), true
+}
+
+func (g *GestureRecognizer) Cancel() {
+	g.pressed = false
+}
+
+// Gesture возвращает распознаватель для кнопки куба
+func (c *Cube) Gesture(name string) *GestureRecognizer {
+	if c.gestures == nil {
+		c.gestures = make(map[string]*GestureRecognizer)
+	}
+	g, ok := c.gestures[name]
+	if !ok {
+		g = &GestureRecognizer{}
+		c.gestures[name] = g
+	}
+	return g
+}
@@ -7,7 +7,6 @@ require (
 	github.com/gorilla/websocket v1.4.2 // indirect
 	github.com/hexops/vecty v0.6.0
 	github.com/llgcode/draw2d v0.0.0-20200110163050-b96d8208fcfc
-	github.com/markfarnan/go-canvas v0.0.0-20200722235510-6971ccd00770
 	golang.org/x/text v0.3.6
 	gopkg.in/olahol/melody.v1 v1.0.0-20170518105555-d52139073376
 )
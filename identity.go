@@ -0,0 +1,263 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"github.com/hexops/vecty/prop"
+	"strings"
+	"syscall/js"
+)
+
+// Identity - сохраненная учетная запись устройства (токен, серийный номер, сервер, питание).
+// Список общий для всех кубов, поэтому учетную запись можно открыть в любом из них.
+type Identity struct {
+	Name string `json:"name"`
+	Configuration
+}
+
+func (c *Cube) APIURL() string {
+	return strings.TrimRight(c.backend, "/") + "/api/v1"
+}
+
+func (c *Cube) WSURL() string {
+	url := strings.TrimRight(c.backend, "/")
+	if strings.HasPrefix(url, "https://") {
+		url = "wss://" + strings.TrimPrefix(url, "https://")
+	} else if strings.HasPrefix(url, "http://") {
+		url = "ws://" + strings.TrimPrefix(url, "http://")
+	}
+	return url + "/ws"
+}
+
+// Configuration - текущее состояние куба в виде, который хранится в настройках
+func (c *Cube) Configuration() Configuration {
+	powerOn := c.powerOn
+	config := Configuration{Version: ConfigVersion, PoweredOn: &powerOn}
+	if c.token != nil {
+		config.Token = *c.token
+	}
+	if c.sn != nil {
+		config.SN = *c.sn
+	}
+	if c.backend != DefaultBackend {
+		config.Backend = c.backend
+	}
+	return config
+}
+
+// StoreConfig сохраняет конфигурацию куба и обновляет выбранную учетную запись
+func (c *Cube) StoreConfig() {
+	config := c.Configuration()
+	configdata, _ := json.Marshal(config)
+	println("Config is ", string(configdata))
+	storage.Set(c.Key("config"), &configdata)
+	if c.identity != "" {
+		StoreIdentity(Identity{Name: c.identity, Configuration: config})
+	}
+}
+
+// список учетных записей читается из хранилища один раз и дальше меняется вместе с ним:
+// панель учетных записей перерисовывается на каждом кадре эффекта подсветки
+var savedIdentities []Identity
+var identitiesLoaded bool
+
+func LoadIdentities() []Identity {
+	if identitiesLoaded {
+		return savedIdentities
+	}
+	if data := storage.Get("identities"); data != nil {
+		if err := json.Unmarshal([]byte(*data), &savedIdentities); err != nil {
+			println("Identities are broken ", err.Error())
+		}
+	}
+	identitiesLoaded = true
+	return savedIdentities
+}
+
+func StoreIdentities(identities []Identity) {
+	savedIdentities = identities
+	identitiesLoaded = true
+	data, _ := json.Marshal(identities)
+	storage.Set("identities", &data)
+}
+
+// StoreIdentity добавляет учетную запись или заменяет запись с тем же именем
+func StoreIdentity(identity Identity) {
+	identities := LoadIdentities()
+	for i := range identities {
+		if identities[i].Name == identity.Name {
+			identities[i] = identity
+			StoreIdentities(identities)
+			return
+		}
+	}
+	StoreIdentities(append(identities, identity))
+}
+
+func (c *Cube) setIdentity(name string) {
+	c.identity = name
+	if name == "" {
+		storage.Set(c.Key("identity"), nil)
+		return
+	}
+	data := []byte(name)
+	storage.Set(c.Key("identity"), &data)
+}
+
+// SaveIdentity сохраняет текущее состояние куба под именем name
+func (c *Cube) SaveIdentity(name string) {
+	c.setIdentity(name)
+	c.StoreConfig()
+	vecty.Rerender(emulator)
+}
+
+// SwitchIdentity переключает куб на сохраненную учетную запись без повторной привязки
+func (c *Cube) SwitchIdentity(name string) {
+	if name == c.identity {
+		return
+	}
+	if name == "" {
+		c.setIdentity("")
+		vecty.Rerender(emulator)
+		return
+	}
+	for _, identity := range LoadIdentities() {
+		if identity.Name != name {
+			continue
+		}
+		println("Switch identity to ", name)
+		c.Disconnect()
+		configdata, _ := json.Marshal(identity.Configuration)
+		storage.Set(c.Key("config"), &configdata)
+		c.setIdentity(name)
+		c.LoadConfig()
+		c.UpdatePowerState()
+		return
+	}
+	println("Unknown identity ", name)
+}
+
+// ConfirmLeave спрашивает, можно ли уйти с несохраненной учетной записи: переключение
+// заменяет конфигурацию куба, и токен текущей привязки будет потерян
+func (c *Cube) ConfirmLeave() bool {
+	if c.identity != "" || c.token == nil {
+		return true
+	}
+	return js.Global().Call("confirm", "The current pairing isn't saved as an identity and will be lost. Switch anyway?").Bool()
+}
+
+// CloneIdentity сохраняет копию текущей учетной записи под новым именем и переключается на нее
+func (c *Cube) CloneIdentity(name string) {
+	StoreIdentity(Identity{Name: name, Configuration: c.Configuration()})
+	c.setIdentity(name)
+	vecty.Rerender(emulator)
+}
+
+// NewIdentity создает учетную запись без токена и переключается на нее;
+// куб выключается, привязка начнется при включении
+func (c *Cube) NewIdentity(name string, backend string) {
+	config := Configuration{Version: ConfigVersion}
+	if backend != DefaultBackend {
+		config.Backend = backend
+	}
+	StoreIdentity(Identity{Name: name, Configuration: config})
+	c.SwitchIdentity(name)
+}
+
+func (c *Cube) DeleteIdentity(name string) {
+	identities := LoadIdentities()
+	for i := range identities {
+		if identities[i].Name == name {
+			StoreIdentities(append(identities[:i], identities[i+1:]...))
+			break
+		}
+	}
+	if c.identity == name {
+		c.setIdentity("")
+	}
+	vecty.Rerender(emulator)
+}
+
+// Prompt спрашивает строку у пользователя; ok = false при отмене или пустом вводе
+func Prompt(question string, value string) (string, bool) {
+	answer := js.Global().Call("prompt", question, value)
+	if answer.IsNull() || answer.IsUndefined() || strings.TrimSpace(answer.String()) == "" {
+		return "", false
+	}
+	return strings.TrimSpace(answer.String()), true
+}
+
+type IdentityPanel struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *IdentityPanel) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	options := vecty.List{elem.Option(vecty.Markup(
+		prop.Value(""),
+		vecty.Property("selected", c.identity == ""),
+	), vecty.Text("unsaved identity"))}
+	for _, identity := range LoadIdentities() {
+		title := identity.Name
+		if identity.Token == "" {
+			title += " (not paired)"
+		}
+		options = append(options, elem.Option(vecty.Markup(
+			prop.Value(identity.Name),
+			vecty.Property("selected", identity.Name == c.identity),
+		), vecty.Text(title)))
+	}
+	button := func(ch string, title string, action func()) *vecty.HTML {
+		return elem.Span(vecty.Markup(
+			vecty.Class("identity-button"),
+			vecty.Property("title", title),
+			On("click", func(event *vecty.Event) {
+				action()
+			}),
+		), vecty.Text(ch))
+	}
+	return elem.Div(vecty.Markup(vecty.Class("centered", "identity")),
+		elem.Select(vecty.Markup(
+			On("change", func(e *vecty.Event) {
+				if !c.ConfirmLeave() {
+					e.Target.Set("value", c.identity)
+					return
+				}
+				c.SwitchIdentity(e.Target.Get("value").String())
+			}),
+		), options),
+		button("\uF0C7", "save identity", func() {
+			if name, ok := Prompt("Identity name", c.identity); ok {
+				c.SaveIdentity(name)
+			}
+		}),
+		button("\uF0C5", "clone identity", func() {
+			if name, ok := Prompt("Name of the copy", c.identity+" copy"); ok {
+				c.CloneIdentity(name)
+			}
+		}),
+		button("\uF067", "new identity", func() {
+			if !c.ConfirmLeave() {
+				return
+			}
+			name, ok := Prompt("Identity name", "")
+			if !ok {
+				return
+			}
+			if backend, ok := Prompt("Backend URL", DefaultBackend); ok {
+				c.NewIdentity(name, backend)
+			}
+		}),
+		vecty.If(c.identity != "", button("\uF014", "delete identity", func() {
+			if js.Global().Call("confirm", "Delete identity "+c.identity+"?").Bool() {
+				c.DeleteIdentity(c.identity)
+			}
+		})),
+		vecty.If(c.backend != DefaultBackend, elem.Span(vecty.Markup(vecty.Class("identity-backend")), vecty.Text(c.backend))),
+	)
+}
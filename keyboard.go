@@ -0,0 +1,206 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"sort"
+	"strings"
+	"syscall/js"
+)
+
+// действия, доступные с клавиатуры
+var keyActions = map[string]func(c *Cube){
+	"up":        func(c *Cube) { c.Up(false) },
+	"down":      func(c *Cube) { c.Down(false) },
+	"top":       func(c *Cube) { c.Up(true) },
+	"bottom":    func(c *Cube) { c.Down(true) },
+	"left":      func(c *Cube) { c.Left() },
+	"right":     func(c *Cube) { c.Right() },
+	"tap":       func(c *Cube) { c.Tap(false) },
+	"longtap":   func(c *Cube) { c.Tap(true) },
+	"menu":      func(c *Cube) { c.Menu() },
+	"flip":      func(c *Cube) { c.Flip() },
+	"shake":     func(c *Cube) { c.Shake() },
+	"power":     func(c *Cube) { c.TogglePower() },
+	"tip-left":  func(c *Cube) { c.Tip(TIP_LEFT) },
+	"tip-right": func(c *Cube) { c.Tip(TIP_RIGHT) },
+	"tip-fwd":   func(c *Cube) { c.Tip(TIP_FORWARD) },
+	"tip-back":  func(c *Cube) { c.Tip(TIP_BACKWARD) },
+	"view3d":    func(c *Cube) { c.Toggle3D() },
+	"help":      func(c *Cube) { ToggleKeyHelp() },
+}
+
+// действия, которые выполняются и при выключенном кубе
+var keyAlwaysEnabled = map[string]bool{"power": true, "view3d": true, "help": true}
+
+var defaultKeymap = map[string]string{
+	"up":        "ArrowUp",
+	"down":      "ArrowDown",
+	"top":       "Shift+ArrowUp",
+	"bottom":    "Shift+ArrowDown",
+	"left":      "ArrowLeft",
+	"right":     "ArrowRight",
+	"tap":       "Enter",
+	"longtap":   "Shift+Enter",
+	"menu":      "m",
+	"flip":      "f",
+	"shake":     "s",
+	"power":     "p",
+	"tip-left":  "Alt+ArrowLeft",
+	"tip-right": "Alt+ArrowRight",
+	"tip-fwd":   "Alt+ArrowUp",
+	"tip-back":  "Alt+ArrowDown",
+	"view3d":    "v",
+	"help":      "?",
+}
+
+// keymap - сочетание клавиш -> действие
+var keymap map[string]string
+
+// куб, которым управляет клавиатура
+var focusedCube int
+
+var showKeyHelp bool
+
+// LoadKeymap берет раскладку по умолчанию и переопределения из настроек ("keymap": {"действие": "клавиши"})
+func LoadKeymap() {
+	bindings := make(map[string]string)
+	for action, combo := range defaultKeymap {
+		bindings[action] = combo
+	}
+	stored := storage.Get("keymap")
+	if stored != nil {
+		var overrides map[string]string
+		if err := json.Unmarshal([]byte(*stored), &overrides); err != nil {
+			println("Keymap is broken")
+		}
+		for action, combo := range overrides {
+			if _, ok := keyActions[action]; !ok {
+				println("Unknown key action ", action)
+				continue
+			}
+			bindings[action] = combo
+		}
+	}
+	keymap = make(map[string]string)
+	for action, combo := range bindings {
+		if combo != "" {
+			keymap[NormalizeCombo(combo)] = action
+		}
+	}
+}
+
+// NormalizeCombo приводит запись вида "shift+M" к виду "Shift+m"
+func NormalizeCombo(combo string) string {
+	parts := strings.Split(combo, "+")
+	key := parts[len(parts)-1]
+	if key == "" && len(parts) > 1 {
+		//сама клавиша "+"
+		key = "+"
+		parts = parts[:len(parts)-2]
+	} else {
+		parts = parts[:len(parts)-1]
+	}
+	var ctrl, alt, shift bool
+	for _, m := range parts {
+		switch strings.ToLower(m) {
+		case "ctrl", "control":
+			ctrl = true
+		case "alt":
+			alt = true
+		case "shift":
+			shift = true
+		}
+	}
+	return comboString(key, ctrl, alt, shift)
+}
+
+func comboString(key string, ctrl, alt, shift bool) string {
+	if len([]rune(key)) == 1 {
+		if strings.ToLower(key) == strings.ToUpper(key) {
+			//для символов Shift уже учтен в самом символе ("?")
+			shift = false
+		}
+		key = strings.ToLower(key)
+	}
+	result := ""
+	if ctrl {
+		result += "Ctrl+"
+	}
+	if alt {
+		result += "Alt+"
+	}
+	if shift {
+		result += "Shift+"
+	}
+	return result + key
+}
+
+func InitKeyboard() {
+	LoadKeymap()
+	js.Global().Get("document").Call("addEventListener", "keydown", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		Update(func() {
+			event := args[0]
+			switch strings.ToLower(event.Get("target").Get("tagName").String()) {
+			case "input", "textarea", "select":
+				return
+			}
+			combo := comboString(event.Get("key").String(),
+				event.Get("ctrlKey").Bool() || event.Get("metaKey").Bool(),
+				event.Get("altKey").Bool(),
+				event.Get("shiftKey").Bool())
+			action, ok := keymap[combo]
+			if !ok || focusedCube >= len(cubes) {
+				return
+			}
+			event.Call("preventDefault")
+			if event.Get("repeat").Bool() {
+				return
+			}
+			c := cubes[focusedCube]
+			if !c.powerOn && !keyAlwaysEnabled[action] {
+				return
+			}
+			keyActions[action](c)
+		})
+		return nil
+	}))
+}
+
+func (c *Cube) Focus() {
+	if focusedCube == c.id {
+		return
+	}
+	focusedCube = c.id
+	vecty.Rerender(emulator)
+}
+
+func ToggleKeyHelp() {
+	showKeyHelp = !showKeyHelp
+	vecty.Rerender(emulator)
+}
+
+// KeyHelp - подсказка по клавишам
+type KeyHelp struct {
+	vecty.Core
+}
+
+func (p *KeyHelp) Render() vecty.ComponentOrHTML {
+	combos := make([]string, 0, len(keymap))
+	for combo := range keymap {
+		combos = append(combos, combo)
+	}
+	sort.Strings(combos)
+	var items vecty.List
+	for _, combo := range combos {
+		items = append(items, elem.Span(vecty.Markup(vecty.Class("key")),
+			elem.KeyboardInput(vecty.Text(combo)),
+			vecty.Text(" "+keymap[combo]),
+		))
+	}
+	return elem.Div(vecty.Markup(vecty.Class("centered", "key-help")), items)
+}
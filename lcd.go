@@ -0,0 +1,189 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"github.com/hexops/vecty/prop"
+	"image"
+	"math"
+	"strconv"
+	"syscall/js"
+)
+
+const defaultPanelGamma = 2.2
+
+// DisplaySettings - имитация экрана куба
+type DisplaySettings struct {
+	LCD      bool    `json:"lcd"`      //квантование в RGB565
+	Gamma    float64 `json:"gamma"`    //гамма панели, 2.2 - без изменений
+	Grid     bool    `json:"grid"`     //промежутки между пикселями
+	Subpixel bool    `json:"subpixel"` //полосы R, G, B внутри пикселя
+	Zoom     int     `json:"zoom"`     //целое увеличение экранов (только в плоском виде)
+}
+
+func DefaultDisplaySettings() DisplaySettings {
+	return DisplaySettings{Gamma: defaultPanelGamma, Zoom: 1}
+}
+
+func (c *Cube) LoadDisplaySettings() {
+	c.display = DefaultDisplaySettings()
+	stored := storage.Get(c.Key("display"))
+	if stored != nil {
+		json.Unmarshal([]byte(*stored), &c.display)
+	}
+	if c.display.Zoom < 1 {
+		c.display.Zoom = 1
+	}
+	if c.display.Gamma <= 0 {
+		c.display.Gamma = defaultPanelGamma
+	}
+	c.lcdLUT = GammaLUT(c.display.Gamma)
+}
+
+// SetDisplaySettings сохраняет настройки и подгоняет canvas под новый масштаб
+func (c *Cube) SetDisplaySettings(s DisplaySettings) {
+	c.display = s
+	c.lcdLUT = GammaLUT(s.Gamma)
+	data, _ := json.Marshal(s)
+	storage.Set(c.Key("display"), &data)
+	vecty.Rerender(emulator)
+	c.ResizeCanvases()
+}
+
+// DisplayZoom - во сколько раз увеличены экраны на странице
+func (c *Cube) DisplayZoom() int {
+	if c.view3D || !c.display.LCD {
+		return 1
+	}
+	return c.display.Zoom
+}
+
+// CanvasScale - сколько точек canvas приходится на пиксель экрана
+func (c *Cube) CanvasScale() int {
+	if !c.display.LCD {
+		return 1
+	}
+	dpr := int(math.Round(js.Global().Get("devicePixelRatio").Float()))
+	if dpr < 1 {
+		dpr = 1
+	}
+	return c.DisplayZoom() * dpr
+}
+
+// Quantize565 отбрасывает младшие биты так же, как контроллер экрана
+func Quantize565(r, g, b byte) (byte, byte, byte) {
+	r5, g6, b5 := r>>3, g>>2, b>>3
+	return r5<<3 | r5>>2, g6<<2 | g6>>4, b5<<3 | b5>>2
+}
+
+// GammaLUT переводит значения из sRGB в яркость панели с заданной гаммой
+func GammaLUT(gamma float64) [256]byte {
+	var lut [256]byte
+	for i := range lut {
+		lut[i] = byte(math.Round(255 * math.Pow(float64(i)/255, gamma/defaultPanelGamma)))
+	}
+	return lut
+}
+
+// RenderLCD увеличивает кадр в scale раз без сглаживания, с сеткой и субпикселями
+func RenderLCD(src []byte, dst *image.RGBA, scale int, s DisplaySettings, lut *[256]byte) {
+	grid := s.Grid && scale >= 3
+	subpixel := s.Subpixel && scale >= 3
+	for y := 0; y < screenHeight; y++ {
+		for x := 0; x < screenWidth; x++ {
+			pos := (y*screenWidth + x) * 4
+			r, g, b := src[pos], src[pos+1], src[pos+2]
+			if s.LCD {
+				r, g, b = Quantize565(r, g, b)
+			}
+			r, g, b = lut[r], lut[g], lut[b]
+			for dy := 0; dy < scale; dy++ {
+				o := dst.PixOffset(x*scale, y*scale+dy)
+				for dx := 0; dx < scale; dx++ {
+					pr, pg, pb := r, g, b
+					if subpixel {
+						switch dx * 3 / scale {
+						case 0:
+							pg, pb = pg/4, pb/4
+						case 1:
+							pr, pb = pr/4, pb/4
+						default:
+							pr, pg = pr/4, pg/4
+						}
+					}
+					if grid && (dx == scale-1 || dy == scale-1) {
+						pr, pg, pb = pr/3, pg/3, pb/3
+					}
+					dst.Pix[o] = pr
+					dst.Pix[o+1] = pg
+					dst.Pix[o+2] = pb
+					dst.Pix[o+3] = 255
+					o += 4
+				}
+			}
+		}
+	}
+}
+
+type DisplayPanel struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *DisplayPanel) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	s := c.display
+	toggle := func(title string, on bool, set func(s *DisplaySettings)) *vecty.HTML {
+		return elem.Span(vecty.Markup(
+			vecty.Class("display-toggle"),
+			vecty.MarkupIf(on, vecty.Class("on")),
+			On("click", func(event *vecty.Event) {
+				next := c.display
+				set(&next)
+				c.SetDisplaySettings(next)
+			}),
+		), vecty.Text(title))
+	}
+	var zooms vecty.List
+	for z := 1; z <= 3; z++ {
+		zooms = append(zooms, elem.Option(vecty.Markup(
+			prop.Value(strconv.Itoa(z)),
+			vecty.Property("selected", z == s.Zoom),
+		), vecty.Text(strconv.Itoa(z)+"x")))
+	}
+	return elem.Span(vecty.Markup(vecty.Class("display")),
+		toggle("LCD", s.LCD, func(s *DisplaySettings) { s.LCD = !s.LCD }),
+		vecty.If(s.LCD,
+			toggle("grid", s.Grid, func(s *DisplaySettings) { s.Grid = !s.Grid }),
+			toggle("RGB", s.Subpixel, func(s *DisplaySettings) { s.Subpixel = !s.Subpixel }),
+			elem.Input(vecty.Markup(
+				prop.Type(prop.TypeNumber),
+				vecty.Attribute("min", 1),
+				vecty.Attribute("max", 3),
+				vecty.Attribute("step", 0.1),
+				vecty.Property("title", "gamma"),
+				prop.Value(strconv.FormatFloat(s.Gamma, 'f', 1, 64)),
+				On("change", func(e *vecty.Event) {
+					gamma, err := strconv.ParseFloat(e.Target.Get("value").String(), 64)
+					if err != nil || gamma <= 0 {
+						return
+					}
+					next := c.display
+					next.Gamma = gamma
+					c.SetDisplaySettings(next)
+				}),
+			)),
+			elem.Select(vecty.Markup(
+				On("change", func(e *vecty.Event) {
+					next := c.display
+					next.Zoom, _ = strconv.Atoi(e.Target.Get("value").String())
+					c.SetDisplaySettings(next)
+				}),
+			), zooms),
+		),
+	)
+}
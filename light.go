@@ -0,0 +1,154 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"github.com/go-playground/colors"
+	"github.com/hexops/vecty"
+	"math"
+	"syscall/js"
+	"time"
+)
+
+const LIGHT_STATIC = "static"
+const LIGHT_BREATHING = "breathing"
+const LIGHT_BLINK = "blink"
+const LIGHT_RAINBOW = "rainbow"
+const LIGHT_FADE = "fade"
+
+const defaultLightPeriod = 2000 //ms
+const lightFrameInterval = 20   //ms
+
+// LightEffect описывает анимацию нижней подсветки
+type LightEffect struct {
+	Effect   string   `json:"effect" example:"breathing"`
+	Colors   []string `json:"colors" example:"#FFFFFF"`
+	Period   int      `json:"period" example:"2000"`
+	Duration int      `json:"duration" example:"0"` //0 - бесконечно
+}
+
+// SetBaseLightColor задает цвет, к которому подсветка возвращается после окончания эффекта
+func (c *Cube) SetBaseLightColor(base colors.Color) {
+	c.baseLightColor = base
+	if c.lightEffect == nil {
+		c.lightColor = base
+	}
+}
+
+func (c *Cube) StartLightEffect(effect LightEffect) {
+	c.lightEffect = &effect
+	c.lightEffectStart = time.Now()
+	if c.lightInterval == nil {
+		if c.lightTick.IsUndefined() {
+			c.lightTick = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+				Update(c.UpdateLight)
+				return nil
+			})
+		}
+		interval := js.Global().Call("setInterval", c.lightTick, lightFrameInterval)
+		c.lightInterval = &interval
+	}
+	c.UpdateLight()
+}
+
+func (c *Cube) StopLightEffect() {
+	c.lightEffect = nil
+	if c.lightInterval != nil {
+		js.Global().Call("clearInterval", *c.lightInterval)
+		c.lightInterval = nil
+	}
+	if c.baseLightColor != nil {
+		c.lightColor = c.baseLightColor
+	}
+}
+
+func (c *Cube) UpdateLight() {
+	if c.lightEffect == nil {
+		return
+	}
+	elapsed := time.Since(c.lightEffectStart)
+	if c.lightEffect.Duration > 0 && elapsed >= time.Duration(c.lightEffect.Duration)*time.Millisecond {
+		c.StopLightEffect()
+	} else {
+		r, g, b := EffectColor(c.lightEffect, elapsed)
+		c.lightColor, _ = colors.RGB(r, g, b)
+	}
+	vecty.Rerender(emulator)
+}
+
+func parseEffectColors(effect *LightEffect) []*colors.RGBColor {
+	var result []*colors.RGBColor
+	for _, c := range effect.Colors {
+		parsed, err := colors.ParseHEX(c)
+		if err == nil {
+			result = append(result, parsed.ToRGB())
+		}
+	}
+	if len(result) == 0 {
+		white, _ := colors.RGB(255, 255, 255)
+		result = append(result, white)
+	}
+	return result
+}
+
+func scaleColor(c *colors.RGBColor, level float64) (uint8, uint8, uint8) {
+	return uint8(float64(c.R) * level), uint8(float64(c.G) * level), uint8(float64(c.B) * level)
+}
+
+// EffectColor вычисляет цвет подсветки в момент elapsed от начала эффекта
+func EffectColor(effect *LightEffect, elapsed time.Duration) (uint8, uint8, uint8) {
+	palette := parseEffectColors(effect)
+	period := time.Duration(defaultLightPeriod) * time.Millisecond
+	if effect.Period > 0 {
+		period = time.Duration(effect.Period) * time.Millisecond
+	}
+	cycle := int(elapsed / period)
+	phase := float64(elapsed%period) / float64(period)
+	current := palette[cycle%len(palette)]
+
+	switch effect.Effect {
+	case LIGHT_BREATHING:
+		//яркость меняется треугольником от 1/3 до полной
+		level := 1 - 2*phase
+		if phase < 0.5 {
+			level = 2 * phase
+		}
+		return scaleColor(current, 1.0/3+level*2/3)
+	case LIGHT_BLINK:
+		if phase < 0.5 {
+			return current.R, current.G, current.B
+		}
+		return 0, 0, 0
+	case LIGHT_RAINBOW:
+		return hueToRGB(phase * 360)
+	case LIGHT_FADE:
+		next := palette[(cycle+1)%len(palette)]
+		mix := func(a, b uint8) uint8 {
+			return uint8(float64(a)*(1-phase) + float64(b)*phase)
+		}
+		return mix(current.R, next.R), mix(current.G, next.G), mix(current.B, next.B)
+	default:
+		return current.R, current.G, current.B
+	}
+}
+
+func hueToRGB(hue float64) (uint8, uint8, uint8) {
+	x := 1 - math.Abs(math.Mod(hue/60, 2)-1)
+	var r, g, b float64
+	switch {
+	case hue < 60:
+		r, g, b = 1, x, 0
+	case hue < 120:
+		r, g, b = x, 1, 0
+	case hue < 180:
+		r, g, b = 0, 1, x
+	case hue < 240:
+		r, g, b = 0, x, 1
+	case hue < 300:
+		r, g, b = x, 0, 1
+	default:
+		r, g, b = 1, 0, x
+	}
+	return uint8(r * 255), uint8(g * 255), uint8(b * 255)
+}
@@ -1,7 +1,10 @@
+//go:build js
+// +build js
+
 package main
 
 import (
-	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
@@ -10,41 +13,19 @@ import (
 	"github.com/hexops/vecty/elem"
 	"github.com/hexops/vecty/prop"
 	"github.com/llgcode/draw2d/draw2dimg"
-	"github.com/markfarnan/go-canvas/canvas"
 	"golang.org/x/text/encoding/charmap"
 	"image"
 	"image/color"
-	"io/ioutil"
 	"log"
-	"math/rand"
 	"net/http"
 	"strconv"
 	"syscall/js"
 	"time"
 )
 
-//const URLPrefix = "http://localhost:8080/api/v1"
-//const WSURL = "ws://localhost:8080/ws"
-
-const screenWidth = 160
-const screenHeight = 128
-
-const URLPrefix = "https://api.aircube.tech/api/v1"
-const WSURL = "wss://api.aircube.tech/ws"
-
-type ScreenContent struct {
-	points []byte
-}
+//const DefaultBackend = "http://localhost:8080"
 
-var screens []ScreenContent
-var cvs []*canvas.Canvas2d
-var descriptors []ScreenDescriptor
-var screenLists [][]ListItem
-var active int
-
-var powerOn = false
-var lightColor colors.Color
-var flipped = false
+const DefaultBackend = "https://api.aircube.tech"
 
 type ScreenDescriptor struct {
 	navigable bool
@@ -58,27 +39,44 @@ type ScreenDescriptor struct {
 
 type ScreenView struct {
 	vecty.Core
-	id int
+	cube *Cube
+	id   int
 }
 
 func (p *ScreenView) Render() vecty.ComponentOrHTML {
+	c := p.cube
 	return elem.Div(
 		vecty.Markup(
 			vecty.Class("screen"+strconv.Itoa(p.id)),
-			vecty.MarkupIf(powerOn && active == p.id, vecty.Class("selected"))),
+			vecty.Style("grid-area", "screen"+strconv.Itoa(p.id)),
+			vecty.Style("width", strconv.Itoa(screenWidth*c.DisplayZoom())+"px"),
+			vecty.MarkupIf(c.view3D, vecty.Class("face"), vecty.Style("transform", FaceTransform(p.id))),
+			vecty.MarkupIf(!c.view3D && c.powerOn && c.active == p.id, vecty.Class("selected"))),
 		elem.Canvas(
 			vecty.Markup(
-				prop.ID("canvas"+strconv.Itoa(p.id)),
+				prop.ID(c.CanvasID(p.id)),
 				vecty.Style("background", "black"),
-				vecty.Property("width", strconv.Itoa(screenWidth)),
-				vecty.Property("height", strconv.Itoa(screenHeight)),
+				vecty.Style("width", strconv.Itoa(screenWidth*c.DisplayZoom())+"px"),
+				vecty.Style("height", strconv.Itoa(screenHeight*c.DisplayZoom())+"px"),
+				vecty.Property("width", strconv.Itoa(screenWidth*c.CanvasScale())),
+				vecty.Property("height", strconv.Itoa(screenHeight*c.CanvasScale())),
 			),
 		),
 	)
 }
 
+// Mount подключает canvas после того, как элемент появился в DOM
+func (p *ScreenView) Mount() {
+	p.cube.AttachCanvas(p.id)
+}
+
+func (p *ScreenView) Unmount() {
+	p.cube.DetachCanvas(p.id)
+}
+
 type FlipButton struct {
 	vecty.Core
+	cube *Cube
 }
 
 const TYPE_TAP = 0
@@ -87,299 +85,251 @@ const TYPE_CHANGE = 2
 const TYPE_LONGTAP = 3
 const TYPE_MENU = 4
 const TYPE_TELEMETRY = 5 //не используется
-const TYPE_ACCEL = 6     //не используется
+const TYPE_ACCEL = 6
 const TYPE_SHAKING = 7
 const TYPE_WIFI_CONNECTED = 8 //не используется
 
-var tapStart time.Time
-
 func (p *FlipButton) Render() vecty.ComponentOrHTML {
+	c := p.cube
 	var ch = "\uF0AA"
-	if flipped {
+	if c.Flipped() {
 		ch = "\uF0AB"
 	}
 
-	fd := 0
-	if flipped {
-		fd = 1
-	}
-
 	return elem.Div(vecty.Markup(vecty.Class("centered")),
 		elem.Div(vecty.Markup(vecty.Class("flip-button"),
-			&vecty.EventListener{Name: "mousedown", Listener: func(event *vecty.Event) {
-				tapStart = time.Now()
-			}},
-			&vecty.EventListener{
-				Name: "mouseup",
-				Listener: func(event *vecty.Event) {
-					var ms = time.Now().Sub(tapStart).Milliseconds()
-					if ms < 500 {
-						flipped = !flipped
-						data, _ := json.Marshal(CubeInfo{Type: TYPE_FLIP, State: &fd})
-						SendToServer(string(data))
-						UpdateScreens()
-						vecty.Rerender(emulator) //short click
-					} else {
-						//эффект "встряхивания"
-						document := js.Global().Get("document")
-						screen0 := document.Call("querySelector", ".screen0")
-						screen0.Get("classList").Call("add", "shaking")
-						screen1 := document.Call("querySelector", ".screen1")
-						screen1.Get("classList").Call("add", "shaking")
-						screen2 := document.Call("querySelector", ".screen2")
-						screen2.Get("classList").Call("add", "shaking")
-						screen3 := document.Call("querySelector", ".screen3")
-						screen3.Get("classList").Call("add", "shaking")
-						go func() {
-							//отправка сообщения на сервер
-							info := CubeInfo{
-								Type: TYPE_SHAKING,
-							}
-							data, _ := json.Marshal(info)
-							SendToServer(string(data))
-							time.Sleep(1500 * time.Millisecond)
-							screen0.Get("classList").Call("remove", "shaking")
-							screen1.Get("classList").Call("remove", "shaking")
-							screen2.Get("classList").Call("remove", "shaking")
-							screen3.Get("classList").Call("remove", "shaking")
-						}()
-					}
-				}},
+			c.Press("flip", FlipThresholds(), func(level int) {
+				if level == 0 {
+					c.Flip() //short click
+				} else {
+					c.Shake()
+				}
+			}),
 		), vecty.Text(ch)))
 }
 
 type LeftButton struct {
 	vecty.Core
+	cube *Cube
 }
 
 func (p *LeftButton) Render() vecty.ComponentOrHTML {
+	c := p.cube
 	return elem.Data(vecty.Markup(vecty.Class("fa-button"),
-		vecty.Class("left"), &vecty.EventListener{Name: "click", Listener: func(event *vecty.Event) {
-			if powerOn {
-				temp := active
-				temp--
-				if temp < 0 {
-					temp = 4 - 1
-				}
-				data, _ := json.Marshal(CubeInfo{Type: TYPE_CHANGE, Screen: &temp})
-				SendToServer(string(data))
-			}
-		}},
+		vecty.Class("left"), On("click", func(event *vecty.Event) {
+			c.Left()
+		}),
 	), vecty.Text("\uF053"))
 }
 
 type RightButton struct {
 	vecty.Core
+	cube *Cube
 }
 
 func (p *RightButton) Render() vecty.ComponentOrHTML {
+	c := p.cube
 	return elem.Data(vecty.Markup(
 		vecty.Class("fa-button"),
 		vecty.Class("right"),
-		&vecty.EventListener{Name: "click", Listener: func(event *vecty.Event) {
-			if powerOn {
-				temp := active
-				temp++
-				temp = temp % 4
-				data, _ := json.Marshal(CubeInfo{Type: TYPE_CHANGE, Screen: &temp})
-				SendToServer(string(data))
-			}
-		}}), vecty.Text("\uF054"))
+		On("click", func(event *vecty.Event) {
+			c.Right()
+		})), vecty.Text("\uF054"))
 }
 
 type Screens struct {
 	vecty.Core
+	cube *Cube
+}
+
+// ScreensTemplate - сетка для числа граней, отличного от 4 (раскладка для 4 граней задана в main.css)
+func ScreensTemplate() string {
+	areas := "left"
+	for i := 0; i < faceCount; i++ {
+		areas += " screen" + strconv.Itoa(i)
+	}
+	return "\"" + areas + " right\" / 64px repeat(" + strconv.Itoa(faceCount) + ", auto) 64px"
 }
 
 func (p *Screens) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	var views vecty.List
+	for i := range c.screens {
+		views = append(views, &ScreenView{cube: c, id: i})
+	}
 	return elem.Div(vecty.Markup(vecty.Class("centered")),
 		elem.Div(
-			vecty.Markup(vecty.Class("screens")),
-			&LeftButton{},
-			&ScreenView{id: 0},
-			&ScreenView{id: 1},
-			&ScreenView{id: 2},
-			&ScreenView{id: 3},
-			&RightButton{},
+			vecty.Markup(vecty.Class("screens"),
+				vecty.MarkupIf(c.DisplayZoom() > 1 || faceCount != 4, vecty.Class("zoomed")),
+				vecty.MarkupIf(faceCount != 4, vecty.Style("grid-template", ScreensTemplate()))),
+			&LeftButton{cube: c},
+			elem.Div(
+				vecty.Markup(vecty.Class("scene"), vecty.MarkupIf(c.view3D, vecty.Class("scene3d")), c.Swipe(),
+					vecty.MarkupIf(c.view3D && faceCount != 4, vecty.Style("grid-column", "2 / "+strconv.Itoa(faceCount+2)))),
+				elem.Div(
+					vecty.Markup(vecty.Class("faces"),
+						vecty.MarkupIf(c.view3D, vecty.Class("cube3d"), vecty.Style("transform", c.CubeTransform()))),
+					views,
+					elem.Div(vecty.Markup(vecty.Class("face-top"))),
+					elem.Div(vecty.Markup(vecty.Class("face-bottom"))),
+				),
+			),
+			&RightButton{cube: c},
 		),
 	)
 }
 
 type PowerOnButton struct {
 	vecty.Core
+	cube *Cube
 }
 
-var socketConnected bool
-var ws js.Value
-
-func SendToServer(s string) {
-	if socketConnected {
-		ws.Call("send", s)
+func (c *Cube) SendToServer(s string) {
+	if c.socketConnected {
+		c.ws.Call("send", s)
 	}
 }
 
-func OnMessage(s string) {
+func (c *Cube) OnMessage(s string) {
 
 	var updateInfo UpdateInfo
 	json.Unmarshal([]byte(s), &updateInfo)
 
 	if updateInfo.Select != nil && *updateInfo.Select {
 		//change selection
-		active = *updateInfo.Screen
+		previous := c.active
+		c.active = *updateInfo.Screen
+		c.FaceToUser(c.active)
 		if updateInfo.Position != nil {
 			//change screen and position
-			descriptors[active].selected = *updateInfo.Position
-			println("Change position on ", active)
-			RenderList(active)
+			c.descriptors[c.active].selected = *updateInfo.Position
+			println("Change position on ", c.active)
+			c.RenderList(c.active)
+		}
+		if previous != c.active && updateInfo.Transition != "" {
+			c.BeginTransition(c.active, updateInfo.Transition, updateInfo.TransitionDuration)
 		}
 		vecty.Rerender(emulator)
 	} else {
 		println("Token")
-		if token != nil {
-			println(*token)
+		if c.token != nil {
+			println(*c.token)
 		}
 		if updateInfo.Screen != nil {
-			if updateInfo.IsText {
-				GetListFromNetwork(*updateInfo.Screen)
+			c.QueueTransition(*updateInfo.Screen, updateInfo.Transition, updateInfo.TransitionDuration)
+			if updateInfo.StopAnimation {
+				//загрузка, начатая раньше, не должна перезапустить анимацию
+				c.CancelRequest(*updateInfo.Screen)
+				c.StopAnimation(*updateInfo.Screen)
+			} else if updateInfo.IsAnimation {
+				c.GetAnimationFromNetwork(*updateInfo.Screen)
 			} else {
-				GetImageFromNetwork(*updateInfo.Screen)
+				c.StopAnimation(*updateInfo.Screen)
+				if updateInfo.IsText {
+					c.GetListFromNetwork(*updateInfo.Screen)
+				} else {
+					c.GetImageFromNetwork(*updateInfo.Screen)
+				}
 			}
 		} else {
 			if updateInfo.Color != "" {
 				//change color
-				lightColor, _ = colors.ParseHEX(updateInfo.Color)
+				lc, err := colors.ParseHEX(updateInfo.Color)
+				if err == nil {
+					c.SetBaseLightColor(lc)
+				}
 				vecty.Rerender(emulator)
 			}
+			if updateInfo.Light != nil {
+				c.StartLightEffect(*updateInfo.Light)
+			}
+			if updateInfo.Sound != nil {
+				c.PlaySound(*updateInfo.Sound)
+			}
 		}
 	}
 }
 
-var blink js.Func
-var brightness uint8
-var brightnessShift int
-
 type HelloMessage struct {
 	Token *string `json:"token"`
 	SN    *uint32 `json:"sn"`
 	Pin   *string `json:"pin"`
+	Nonce *string `json:"nonce,omitempty"`
 }
 
-var sn *uint32
-
-func LoggedIn(relogin bool) {
+func (c *Cube) LoggedIn(relogin bool) {
 	println("Logged in")
 	hello := HelloMessage{
-		Token: token,
-		SN:    sn,
+		Token: c.token,
+		SN:    c.sn,
 		Pin:   nil,
 	}
 	hello_json, _ := json.Marshal(hello)
 	if relogin {
 		println("Relogin")
-		SendToServer(string(hello_json))
+		c.SendToServer(string(hello_json))
 	}
 	println("Send hello message ", hello_json)
-	ws.Call("addEventListener", "open", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		SendToServer(string(hello_json))
+	c.ws.Call("addEventListener", "open", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		Update(func() {
+			c.SendToServer(string(hello_json))
+		})
 		return nil
 	}))
-	ws.Call("addEventListener", "close", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		ws = js.Global().Get("WebSocket").New(WSURL)
-		socketConnected = true
+	c.ws.Call("addEventListener", "close", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		Update(func() {
+			//переподключаемся, только если закрылось текущее соединение и его не закрыли мы сами
+			if !c.socketConnected || !this.Equal(c.ws) || c.registration_mode {
+				return
+			}
+			c.ws = js.Global().Get("WebSocket").New(c.WSURL())
+			c.socketConnected = true
+		})
 		return nil
 	}))
-	ws.Call("addEventListener", "message", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+	c.ws.Call("addEventListener", "message", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
 		arg0 := args[0].Get("data").String()
 		println("Message accepted ", arg0)
-		OnMessage(arg0)
+		Update(func() {
+			c.OnMessage(arg0)
+		})
 		return nil
 	}))
 }
 
-func PoweringOn() {
-	lightColor, _ = colors.RGBA(31, 191, 191, 1)
+func (c *Cube) PoweringOn() {
+	lc, _ := colors.RGBA(31, 191, 191, 1)
+	c.SetBaseLightColor(lc)
 	//check init mode
-	ws = js.Global().Get("WebSocket").New(WSURL)
-	socketConnected = true
-	if !registration_mode {
+	c.ws = js.Global().Get("WebSocket").New(c.WSURL())
+	c.socketConnected = true
+	if !c.registration_mode {
 		//register js function
-		LoggedIn(false)
+		c.LoggedIn(false)
+		c.RestoreCache()
 	} else {
-		Register()
+		c.Register()
 	}
 }
 
-func Register() {
-	rand.Seed(time.Now().Unix())
-	pin := rand.Intn(10000)
-	DrawDigit(0, int(pin/1000))
-	DrawDigit(1, (pin%1000)/100)
-	DrawDigit(2, (pin%100)/10)
-	DrawDigit(3, pin%10)
-	DrawBorder(0, 8, 48, 16, 87)
-	DrawBorder(1, 8, 110, 50, 181)
-	DrawBorder(2, 8, 153, 82, 235)
-	DrawBorder(3, 8, 191, 144, 245)
-	brightness = 128
-	brightnessShift = 2
-	blink = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		brightness = uint8(int(brightness) + brightnessShift)
-		if brightness > 192 || brightness < 64 {
-			brightnessShift = -brightnessShift
-		}
-		lightColor, _ = colors.RGB(brightness, brightness, brightness)
-		vecty.Rerender(emulator)
-		return nil
-	})
-	js.Global().Call("setInterval", blink, 20)
-	pinstr := fmt.Sprintf("%04d", pin)
-	ws.Call("addEventListener", "open", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		hello := HelloMessage{
-			Token: nil,
-			SN:    nil,
-			Pin:   &pinstr,
-		}
-		hello_json, _ := json.Marshal(hello)
-		SendToServer(string(hello_json))
-		return nil
-	}))
-	ws.Call("addEventListener", "message", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
-		if registration_mode {
-			arg0 := args[0].Get("data").String()
-			var db DeviceBound
-			err := json.Unmarshal([]byte(arg0), &db)
-			if err == nil {
-				token = &db.Token
-				sn = &db.SN
-				registration_mode = false
-				var poweredOn = true
-				println("Token is ", *token)
-
-				config := Configuration{
-					Token:     *token,
-					SN:        *sn,
-					PoweredOn: &poweredOn,
-				}
-				configdata, _ := json.Marshal(config)
-				println("Config is ", configdata)
-				StoreToLocalStorage("config", &configdata)
-				LoggedIn(true)
-			}
-		}
-		return nil
-	}))
-}
-
-func UpdatePowerState() {
-	if powerOn {
-		PoweringOn()
+func (c *Cube) UpdatePowerState() {
+	if c.powerOn {
+		c.PoweringOn()
 	} else {
-		if socketConnected {
-			ws.Call("close")
-			socketConnected = false
+		if c.socketConnected {
+			c.ws.Call("close")
+			c.socketConnected = false
 		}
-		lightColor = colors.FromStdColor(color.Black)
-		ClearScreens()
+		c.StopRegistration()
+		c.CancelRequests()
+		c.ClearErrors()
+		c.StopLightEffect()
+		c.SetBaseLightColor(colors.FromStdColor(color.Black))
+		c.StopAnimations()
+		if c.soundPlaying {
+			c.StopSound()
+		}
+		c.ClearScreens()
 	}
 	print("Rerender")
 	print(emulator)
@@ -387,24 +337,14 @@ func UpdatePowerState() {
 }
 
 func (p *PowerOnButton) Render() vecty.ComponentOrHTML {
+	c := p.cube
 	return elem.Section(
 		elem.Anchor(vecty.Markup(
 			vecty.Class("beveled-button"),
-			&vecty.EventListener{
-				Name: "click",
-				Listener: func(event *vecty.Event) {
-					powerOn = !powerOn
-					var state = GetFromLocalStorage("config")
-					var config Configuration
-					print("Current config is ", state)
-					json.Unmarshal([]byte(*state), &config)
-					config.PoweredOn = &powerOn
-					configdata, _ := json.Marshal(config)
-					StoreToLocalStorage("config", &configdata)
-					UpdatePowerState()
-				},
-			},
-			vecty.MarkupIf(powerOn, vecty.Class("on"))),
+			On("click", func(event *vecty.Event) {
+				c.TogglePower()
+			}),
+			vecty.MarkupIf(c.powerOn, vecty.Class("on"))),
 			vecty.Text("\uF011"),
 		),
 		elem.Span(),
@@ -412,113 +352,39 @@ func (p *PowerOnButton) Render() vecty.ComponentOrHTML {
 }
 
 type DeviceBound struct {
-	SN    uint32 `json:"sn"`
-	Token string `json:"token"`
+	SN    uint32  `json:"sn"`
+	Token string  `json:"token"`
+	Error *string `json:"error"` //сервер не смог привязать куб
 }
 
 type ButtonPanel struct {
 	vecty.Core
+	cube *Cube
 }
 
 func (p *ButtonPanel) Render() vecty.ComponentOrHTML {
+	c := p.cube
 	return elem.Div(
 		vecty.Markup(
 			vecty.Class("centered")),
 		elem.Anchor(vecty.Markup(vecty.Class("touch-button"),
-			&vecty.EventListener{Name: "mousedown", Listener: func(event *vecty.Event) {
-				tapStart = time.Now()
-			}},
-			&vecty.EventListener{
-				Name: "mouseup",
-				Listener: func(event *vecty.Event) {
-					var ms = time.Now().Sub(tapStart).Milliseconds()
-					if descriptors[active].navigable {
-						if ms < 1000 {
-							pos := descriptors[active].selected
-							pos--
-							if pos < 0 {
-								pos = descriptors[active].count - 1
-							}
-							data, _ := json.Marshal(CubeInfo{Type: TYPE_CHANGE, Screen: &active, State: &pos})
-							SendToServer(string(data))
-						} else {
-							pos := 0
-							data, _ := json.Marshal(CubeInfo{
-								Type:   TYPE_CHANGE,
-								Screen: &active,
-								State:  &pos,
-							})
-							SendToServer(string(data))
-						}
-					}
-				}},
+			c.Press("up", JumpThresholds(), func(level int) {
+				c.Up(level > 0)
+			}),
 		), vecty.Text("\uF077")),
 		elem.Anchor(vecty.Markup(vecty.Class("touch-button"),
-			&vecty.EventListener{Name: "mousedown", Listener: func(event *vecty.Event) {
-				tapStart = time.Now()
-			}},
-			&vecty.EventListener{
-				Name: "mouseup",
-				Listener: func(event *vecty.Event) {
-					var ms = time.Now().Sub(tapStart).Milliseconds()
-					var tap CubeInfo
-					var tapType int
-					if ms < 2000 {
-						tapType = TYPE_TAP
-						if ms >= 1000 {
-							tapType = TYPE_LONGTAP
-						}
-						if descriptors[active].navigable {
-							tap = CubeInfo{
-								Type:   tapType,
-								Screen: &active,
-								State:  &screenLists[active][descriptors[active].selected].Number,
-							}
-						} else {
-							tap = CubeInfo{
-								Type:   tapType,
-								Screen: &active,
-								State:  nil,
-							}
-						}
-					} else {
-						tap = CubeInfo{
-							Type: TYPE_MENU,
-						}
-					}
-					data, _ := json.Marshal(tap)
-					SendToServer(string(data))
-				},
-			}), vecty.Text("\uF058")),
+			c.Press("tap", TapThresholds(), func(level int) {
+				if level < 2 {
+					c.Tap(level == 1)
+				} else {
+					c.Menu()
+				}
+			}),
+		), vecty.Text("\uF058")),
 		elem.Anchor(vecty.Markup(vecty.Class("touch-button"),
-			&vecty.EventListener{Name: "mousedown", Listener: func(event *vecty.Event) {
-				tapStart = time.Now()
-			}},
-			&vecty.EventListener{
-				Name: "mouseup",
-				Listener: func(event *vecty.Event) {
-					var ms = time.Now().Sub(tapStart).Milliseconds()
-
-					if descriptors[active].navigable {
-						if ms < 1000 {
-							pos := descriptors[active].selected
-							pos++
-							if pos >= descriptors[active].count {
-								pos = 0
-							}
-							data, _ := json.Marshal(CubeInfo{Type: TYPE_CHANGE, Screen: &active, State: &pos})
-							SendToServer(string(data))
-						} else {
-							pos := descriptors[active].count - 1
-							data, _ := json.Marshal(CubeInfo{
-								Type:   TYPE_CHANGE,
-								Screen: &active,
-								State:  &pos,
-							})
-							SendToServer(string(data))
-						}
-					}
-				}},
+			c.Press("down", JumpThresholds(), func(level int) {
+				c.Down(level > 0)
+			}),
 		), vecty.Text("\uF078")),
 	)
 }
@@ -529,24 +395,60 @@ type Emulator struct {
 
 var font []byte
 
+const fontURL = "fonts/8x8.fnt"
+const fontSize = 256 * 8 //8 строк на каждый символ Windows-1251
+
+// LoadFont загружает шрифт через общий клиент с повторами; без шрифта текст не выводится
+func LoadFont() {
+	var resp *FetchResponse
+	var err error
+	for attempt := 0; ; attempt++ {
+		resp, err = fetch(context.Background(), fontURL, nil)
+		if err == nil && resp.Status < 500 {
+			break
+		}
+		if attempt == fetchRetries {
+			break
+		}
+		time.Sleep(fetchBackoff << uint(attempt))
+	}
+	if err != nil {
+		println("Font isn't loaded ", err.Error())
+		return
+	}
+	if resp.Status != http.StatusOK || len(resp.Body) < fontSize {
+		println("Font isn't found ", resp.Status, len(resp.Body))
+		return
+	}
+	Update(func() {
+		font = resp.Body
+		for _, c := range cubes {
+			c.RedrawText()
+		}
+	})
+}
+
 func (p *Emulator) Render() vecty.ComponentOrHTML {
+	var views vecty.List
+	for _, c := range cubes {
+		views = append(views, &CubeView{cube: c})
+	}
 	return elem.Body(
-		&PowerOnButton{},
-		&ButtonPanel{},
-		&Screens{},
-		&BottomLight{},
-		&FlipButton{},
+		views,
+		&CubesToolbar{},
+		vecty.If(showKeyHelp, &KeyHelp{}),
 	)
 }
 
 type BottomLight struct {
 	vecty.Core
+	cube *Cube
 }
 
 func (p *BottomLight) Render() vecty.ComponentOrHTML {
 	return elem.Div(
 		vecty.Markup(
-			vecty.Style("background", lightColor.ToHEX().String()),
+			vecty.Style("background", p.cube.lightColor.ToHEX().String()),
 			vecty.Class("bottom-light"),
 		),
 	)
@@ -554,111 +456,50 @@ func (p *BottomLight) Render() vecty.ComponentOrHTML {
 
 var emulator *Emulator
 
-func ClearScreen(screen int) {
-	j := 0
-	pixels := screens[screen].points
-	for j < screenWidth*screenHeight {
-		pixels[j*4] = 0
-		pixels[j*4+1] = 0
-		pixels[j*4+2] = 0
-		pixels[j*4+3] = 255
-		j++
-	}
-}
-
-func ClearScreens() {
-	for i := 0; i < 4; i++ {
-		ClearScreen(i)
-	}
-}
-
-func GetFromLocalStorage(key string) *string {
-	ls := js.Global().Get("localStorage").Get(key)
-	if ls.IsNull() {
-		return nil
-	}
-	lss := ls.String()
-	return &lss
-}
-
-func StoreToLocalStorage(key string, data *[]byte) {
-	if data == nil {
-		js.Global().Get("localStorage").Call("removeItem", key)
-	} else {
-		js.Global().Get("localStorage").Set(key, string(*data))
-	}
-}
+// версия формата Configuration, см. StorageSchema
+const ConfigVersion = 2
 
 type Configuration struct {
+	Version   int    `json:"version,omitempty"`
 	Token     string `json:"token"`
 	SN        uint32 `json:"sn"`
 	PoweredOn *bool  `json:"powered_on"`
+	Backend   string `json:"backend,omitempty"` //адрес сервера, по умолчанию DefaultBackend
 }
 
-var token *string
-var registration_mode bool
-
 func main() {
-	socketConnected = false
-
-	c := GetFromLocalStorage("config")
-	token = nil
-	if c == nil {
-		//goto registration mode
-		registration_mode = true
-	} else {
-		var conf Configuration
-		err := json.Unmarshal([]byte(*c), &conf)
-		if err != nil || conf.Token == "" {
-			registration_mode = true
-			//goto registration mode
-		} else {
-			token = &conf.Token
-			sn = &conf.SN
-			registration_mode = false
-			if conf.PoweredOn != nil {
-				powerOn = *conf.PoweredOn
-			}
+	//открытие IndexedDB ждет ответа браузера, поэтому до блокировки состояния
+	InitStorage()
+	stateMu.Lock()
+	LoadProfile()
+
+	count := 1
+	stored := storage.Get("cubes")
+	if stored != nil {
+		n, err := strconv.Atoi(*stored)
+		if err == nil && n > 0 {
+			count = n
 		}
 	}
-	//init screens
-	for i := 0; i < 4; i++ {
-		screen := ScreenContent{}
-		screen.points = make([]byte, screenWidth*screenHeight*4)
-		screens = append(screens, screen)
-		descriptor := ScreenDescriptor{navigable: false, topY: 0, topLine: 0, selected: 0}
-		descriptors = append(descriptors, descriptor)
-		items := make([]ListItem, 0, 0)
-		screenLists = append(screenLists, items)
+	for i := 0; i < count; i++ {
+		cubes = append(cubes, NewCube(i))
 	}
 
 	vecty.SetTitle("AirCube Emulator")
 	vecty.AddStylesheet("main.css")
 
-	lightColor = colors.FromStdColor(color.Black)
-
+	LoadGestureTimings()
+	InitKeyboard()
+	InitDeviceMotion()
+	InitGamepads()
 	emulator = &Emulator{}
 	vecty.RenderInto("body", emulator)
-	UpdatePowerState()
-
-	for i := 0; i < 4; i++ {
-		d := js.Global().Get("document").Call("querySelector", "#canvas"+strconv.Itoa(i))
-		cv, _ := canvas.NewCanvas2d(false)
-		cv.Set(d, screenWidth, screenHeight)
-		cv.Start(60, MakeRenderCanvas(i))
-		cvs = append(cvs, cv)
+	for _, c := range cubes {
+		c.UpdatePowerState()
 	}
+	stateMu.Unlock()
 
-	go func() {
-		req, _ := http.NewRequest("GET", "fonts/8x8.fnt", nil)
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil {
-			log.Fatalln("Font isn't found")
-		}
-		font, _ = ioutil.ReadAll(resp.Body)
-
-	}()
+	go LoadFont()
 
 	select {}
 }
@@ -689,15 +530,19 @@ func EncodeWindows1251(ba []uint8) []uint8 {
 	return []uint8(out)
 }
 
-func PrintTextLine(win1251 []byte, base_shift int, screen int, x int, y int, r byte, g byte, b byte, size *int) {
+func (c *Cube) PrintTextLine(win1251 []byte, base_shift int, screen int, x int, y int, r byte, g byte, b byte, size *int) {
 
 	sz := 1
 	if size != nil {
 		sz = *size
 	}
 	log.Println("Size is ", sz)
-	for c := 0; c < len(win1251); c++ {
-		var ch = win1251[c]
+	//шрифт загружается после первого вывода, текст дорисует RedrawText
+	if font == nil {
+		return
+	}
+	for i := 0; i < len(win1251); i++ {
+		var ch = win1251[i]
 		pos := int(ch) * 8
 
 		for cy := 0; cy < 8; cy++ {
@@ -709,7 +554,7 @@ func PrintTextLine(win1251 []byte, base_shift int, screen int, x int, y int, r b
 							if y+cy*sz+dy >= screenHeight || y+cy*sz+dy < base_shift {
 								continue
 							}
-							SetPixel(screen, x+cx*sz+dx, y+cy*sz+dy, r, g, b)
+							c.SetPixel(screen, x+cx*sz+dx, y+cy*sz+dy, r, g, b)
 						}
 					}
 				}
@@ -724,35 +569,35 @@ func PrintTextLine(win1251 []byte, base_shift int, screen int, x int, y int, r b
 	}
 }
 
-func RenderList(screen int) {
+func (c *Cube) RenderList(screen int) {
 	log.Println("Render list for ", screen)
-	ClearScreen(screen)
-	list := screenLists[screen]
-	descriptors[screen].count = len(list)
+	c.ClearScreen(screen)
+	list := c.screenLists[screen]
+	c.descriptors[screen].count = len(list)
 
-	top_shift := descriptors[screen].topY
-	top_line := descriptors[screen].topLine
-	selt := descriptors[screen].selected
+	top_shift := c.descriptors[screen].topY
+	top_line := c.descriptors[screen].topLine
+	selt := c.descriptors[screen].selected
 
 	println(selt)
 	println(len(list))
-	println(descriptors[screen].navigable)
+	println(c.descriptors[screen].navigable)
 
 	baseShift := 0
-	if descriptors[screen].title != nil {
+	if c.descriptors[screen].title != nil {
 		baseShift = 24
-		win1251 := EncodeWindows1251([]uint8(*descriptors[screen].title))
+		win1251 := EncodeWindows1251([]uint8(*c.descriptors[screen].title))
 		size := 1
-		PrintTextLine(win1251, 0, screen, 8, 8, 255, 255, 255, &size)
+		c.PrintTextLine(win1251, 0, screen, 8, 8, 255, 255, 255, &size)
 		for x := 0; x <= screenWidth; x++ {
-			SetPixel(screen, x, 20, 255, 255, 255)
+			c.SetPixel(screen, x, 20, 255, 255, 255)
 		}
 	}
-	if selt >= len(list) && descriptors[screen].navigable {
+	if selt >= len(list) && c.descriptors[screen].navigable {
 		return
 	}
 
-	if descriptors[screen].navigable {
+	if c.descriptors[screen].navigable {
 		sely := list[selt].Y - top_shift
 		//scroll up
 		if sely+8 >= screenHeight-baseShift {
@@ -766,9 +611,9 @@ func RenderList(screen int) {
 				}
 				shift++
 			}
-			descriptors[screen].topLine += shift
-			descriptors[screen].topY += delta
-			top_shift = descriptors[screen].topY
+			c.descriptors[screen].topLine += shift
+			c.descriptors[screen].topY += delta
+			top_shift = c.descriptors[screen].topY
 		}
 		println("TopShift is ", top_shift)
 		if sely < 0 {
@@ -795,12 +640,12 @@ func RenderList(screen int) {
 				}
 				shift++
 			}
-			descriptors[screen].topLine -= shift
-			if descriptors[screen].topLine < 0 {
-				descriptors[screen].topLine = 0
+			c.descriptors[screen].topLine -= shift
+			if c.descriptors[screen].topLine < 0 {
+				c.descriptors[screen].topLine = 0
 			}
-			descriptors[screen].topY -= delta
-			top_shift = descriptors[screen].topY
+			c.descriptors[screen].topY -= delta
+			top_shift = c.descriptors[screen].topY
 		}
 	}
 
@@ -840,7 +685,7 @@ func RenderList(screen int) {
 					pos := iy*(*list[i].IconWidth) + ix
 					screen_pos := (y+iy)*screenWidth + (x + ix)
 					if y+iy < screenHeight && y+iy >= baseShift && x+ix >= 0 && x+ix < screenWidth {
-						SetPoint(screen, icon, pos, screen_pos)
+						c.SetPoint(screen, icon, pos, screen_pos)
 					}
 				}
 			}
@@ -853,9 +698,9 @@ func RenderList(screen int) {
 		}
 		size := list[i].Size
 		log.Println("Printing text line at ", x, y+screenHeight, text)
-		PrintTextLine(win1251, baseShift, screen, x, y+yshift, r, g, b, size)
+		c.PrintTextLine(win1251, baseShift, screen, x, y+yshift, r, g, b, size)
 
-		if active == screen && descriptors[screen].selected == i && descriptors[screen].navigable {
+		if c.active == screen && c.descriptors[screen].selected == i && c.descriptors[screen].navigable {
 			lines := (len(win1251) + line_width) / line_width
 			var left, right, top, bottom int
 			if lines <= 1 {
@@ -871,99 +716,84 @@ func RenderList(screen int) {
 			}
 			if top < screenHeight && top >= baseShift {
 				for x := left; x <= right; x++ {
-					SetPixel(screen, x, top, 255, 255, 255)
+					c.SetPixel(screen, x, top, 255, 255, 255)
 				}
 			}
 			if bottom < screenHeight && bottom >= baseShift {
 				for x := left; x <= right; x++ {
-					SetPixel(screen, x, bottom, 255, 255, 255)
+					c.SetPixel(screen, x, bottom, 255, 255, 255)
 				}
 			}
 			for y := top; y <= bottom; y++ {
 				if y < screenHeight && y >= baseShift {
-					SetPixel(screen, left, y, 255, 255, 255)
+					c.SetPixel(screen, left, y, 255, 255, 255)
 				}
 			}
 			for y := top; y <= bottom; y++ {
 				if y < screenHeight && y >= baseShift {
-					SetPixel(screen, right, y, 255, 255, 255)
+					c.SetPixel(screen, right, y, 255, 255, 255)
 				}
 			}
 		}
 	}
 }
 
-func SetPoint(screen int, img []byte, i int, pos int) {
-	var point uint16
-	if i*2+1 < len(img) {
-		point = uint16(img[i*2+1])
-		point = point << 8
-		point = point + uint16(img[i*2])
-		b := point % 32
-		g := (point >> 5) % 64
-		r := (point >> 11) % 32
-		if !flipped {
-			pos = screenHeight*screenWidth - 1 - pos
+func (c *Cube) SetScreen(screen int, img []byte, mode ScanMode) {
+	if c.powerOn {
+		for i := 0; i < screenWidth*screenHeight; i++ {
+			x, y := mode.Position(i, screenWidth, screenHeight)
+			c.SetPoint(screen, img, i, (screenHeight-1-y)*screenWidth+(screenWidth-1-x))
 		}
-		screens[screen].points[pos*4] = byte(r << 3)
-		screens[screen].points[pos*4+1] = byte(g << 2)
-		screens[screen].points[pos*4+2] = byte(b << 3)
-		screens[screen].points[pos*4+3] = 255
 	}
 }
 
-func SetScreen(screen int, img []byte) {
-	if powerOn {
-		i := 0
-		for x := 0; x < screenWidth; x++ {
-			for y := 0; y < screenHeight; y++ {
-				SetPoint(screen, img, i, (screenWidth-1-x)+screenWidth*y)
-				i++
+func (c *Cube) GetImageFromNetwork(screen int) {
+	go c.FetchScreen(screen, "/screen/"+strconv.Itoa(screen), CACHE_IMAGE, func(resp *FetchResponse) {
+		if resp.Status == http.StatusNotModified {
+			if cached := c.CachedScreen(screen); cached != nil {
+				c.ShowCachedScreen(screen, cached)
 			}
-		}
-		//for i := 0; i < len(img)/2; i++ {
-		//	SetPoint(screen, img, i, i)
-		//}
-	}
-}
-
-func GetImageFromNetwork(screen int) {
-	go func() {
-		req, _ := http.NewRequest("GET", URLPrefix+"/screen/"+strconv.Itoa(screen), nil)
-		req.Header.Set("Authorization", "bearer "+*token)
-		client := &http.Client{}
-		resp, err := client.Do(req)
-		if err != nil || resp.StatusCode != 200 {
 			return
 		}
-		content, _ := ioutil.ReadAll(resp.Body)
+		mode := profile.Scan.WithHeaders(resp.Header)
+		c.StoreCachedScreen(screen, CACHE_IMAGE, resp.Body, resp.Header, mode)
 		//rotate!!!
-		SetScreen(screen, content)
-	}()
+		c.StartTransition(screen)
+		c.ShowImage(screen, resp.Body, mode)
+	})
 }
 
-func GetListFromNetwork(screen int) {
-	go func() {
-		client := &http.Client{}
-		print("Token is ")
-		println(token)
-		req2, err2 := http.NewRequest("GET", URLPrefix+"/list/"+strconv.Itoa(screen), nil)
-		req2.Header.Set("Authorization", "bearer "+*token)
-		resp2, _ := client.Do(req2)
-		if err2 != nil || resp2.StatusCode == 404 {
+func (c *Cube) ShowImage(screen int, content []byte, mode ScanMode) {
+	c.descriptors[screen].list = false
+	c.SetScreen(screen, content, mode)
+}
+
+func (c *Cube) GetListFromNetwork(screen int) {
+	go c.FetchScreen(screen, "/list/"+strconv.Itoa(screen), CACHE_LIST, func(resp *FetchResponse) {
+		if resp.Status == http.StatusNotModified {
+			if cached := c.CachedScreen(screen); cached != nil {
+				c.ShowCachedScreen(screen, cached)
+			}
 			return
 		}
 		var result ListDescriptor
-		content, _ := ioutil.ReadAll(resp2.Body)
-		decoder := json.NewDecoder(bytes.NewReader(content))
-		decoder.Decode(&result)
-		screenLists[screen] = result.Items
-		descriptors[screen].title = result.Title
-		descriptors[screen].navigable = result.Navigable
-		descriptors[screen].topY = 0
-		descriptors[screen].list = true
-		UpdateScreen(screen)
-	}()
+		if err := json.Unmarshal(resp.Body, &result); err != nil {
+			c.ReportError(screen, err)
+			return
+		}
+		c.StoreCachedScreen(screen, CACHE_LIST, resp.Body, resp.Header, profile.Scan)
+		c.StartTransition(screen)
+		c.ShowList(screen, result)
+	})
+}
+
+func (c *Cube) ShowList(screen int, result ListDescriptor) {
+	c.screenLists[screen] = result.Items
+	c.descriptors[screen].title = result.Title
+	c.descriptors[screen].navigable = result.Navigable
+	c.descriptors[screen].topY = 0
+	c.descriptors[screen].list = true
+	c.UpdateScreen(screen)
 }
 
 type ListDescriptor struct {
@@ -972,60 +802,98 @@ type ListDescriptor struct {
 	Items     []ListItem `json:"items"`
 }
 
-func UpdateScreens() {
-	if powerOn {
-		for i := 0; i < 4; i++ {
-			UpdateScreen(i)
+func (c *Cube) UpdateScreens() {
+	if c.powerOn {
+		for i := range c.screens {
+			c.UpdateScreen(i)
+		}
+	}
+}
+
+// RedrawText перерисовывает экраны с текстом, выведенные до загрузки шрифта
+func (c *Cube) RedrawText() {
+	if !c.powerOn {
+		return
+	}
+	if c.registration_mode && c.reg.state == REG_WAITING {
+		c.DrawPairing()
+		return
+	}
+	for i := range c.screens {
+		if c.animations[i] == nil && c.descriptors[i].list {
+			c.RenderList(i)
 		}
 	}
 }
 
-func UpdateScreen(screen int) {
-	if descriptors[screen].list {
+func (c *Cube) UpdateScreen(screen int) {
+	if c.animations[screen] != nil {
+		c.animations[screen].Redraw()
+	} else if c.descriptors[screen].list {
 		println("Update screen ", screen)
-		RenderList(screen)
+		c.RenderList(screen)
 	} else {
-		GetImageFromNetwork(screen)
+		c.GetImageFromNetwork(screen)
 	}
 }
 
-func SetPixel(screen int, x int, y int, r byte, g byte, b byte) {
-
+func (c *Cube) SetPixel(screen int, x int, y int, r byte, g byte, b byte) {
+	if x < 0 || y < 0 || x >= screenWidth || y >= screenHeight {
+		return
+	}
 	sh := y*screenWidth + x
-	if flipped {
+	if c.rotations[screen] == 180 {
 		sh = screenHeight*screenWidth - 1 - sh
 	}
 
-	screens[screen].points[sh*4] = r
-	screens[screen].points[sh*4+1] = g
-	screens[screen].points[sh*4+2] = b
-	screens[screen].points[sh*4+3] = 255
+	c.screens[screen].points[sh*4] = r
+	c.screens[screen].points[sh*4+1] = g
+	c.screens[screen].points[sh*4+2] = b
+	c.screens[screen].points[sh*4+3] = 255
 }
 
-func DrawBorder(screen int, width int, r byte, g byte, b byte) {
+func (c *Cube) DrawBorder(screen int, width int, r byte, g byte, b byte) {
 	for y := 0; y < width; y++ {
 		for x := 0; x < screenWidth; x++ {
-			SetPixel(screen, x, y, r, g, b)
+			c.SetPixel(screen, x, y, r, g, b)
 		}
 	}
 	for y := screenHeight - width; y < screenHeight; y++ {
 		for x := 0; x < screenWidth; x++ {
-			SetPixel(screen, x, y, r, g, b)
+			c.SetPixel(screen, x, y, r, g, b)
 		}
 	}
 	for x := 0; x < width; x++ {
 		for y := 0; y < screenHeight; y++ {
-			SetPixel(screen, x, y, r, g, b)
+			c.SetPixel(screen, x, y, r, g, b)
 		}
 	}
 	for x := screenWidth - width; x < screenWidth; x++ {
 		for y := 0; y < screenHeight; y++ {
-			SetPixel(screen, x, y, r, g, b)
+			c.SetPixel(screen, x, y, r, g, b)
 		}
 	}
 }
 
-func DrawDigit(screen int, digit int) {
+var pinBorders = [][3]byte{{48, 16, 87}, {110, 50, 181}, {153, 82, 235}, {191, 144, 245}}
+
+// DrawPIN выводит по цифре на грань; если граней меньше четырех, PIN пишется текстом на первой
+func (c *Cube) DrawPIN(pin int) {
+	if len(c.screens) < 4 {
+		c.ClearScreen(0)
+		size := 2
+		c.PrintTextLine([]byte(fmt.Sprintf("PIN %04d", pin)), 0, 0, 8, screenHeight/2-8, 255, 255, 255, &size)
+		c.DrawBorder(0, 8, pinBorders[0][0], pinBorders[0][1], pinBorders[0][2])
+		return
+	}
+	digits := []int{pin / 1000, (pin % 1000) / 100, (pin % 100) / 10, pin % 10}
+	for i, d := range digits {
+		c.DrawDigit(i, d)
+		c.DrawBorder(i, 8, pinBorders[i][0], pinBorders[i][1], pinBorders[i][2])
+	}
+}
+
+func (c *Cube) DrawDigit(screen int, digit int) {
 	var digits []byte
 	digits = make([]byte, 128, 128)
 	digits = []byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
@@ -1149,19 +1017,24 @@ func DrawDigit(screen int, digit int) {
 		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
 	}
 
-	ClearScreen(screen)
+	c.ClearScreen(screen)
+	//цифра 32x32 растягивается на высоту экрана
+	scale := screenHeight / 32
+	if screenWidth/32 < scale {
+		scale = screenWidth / 32
+	}
 	digitSize := 4
 	pos := digit * 8 * digitSize * digitSize
-	y := 0
-	x := 16
+	y := (screenHeight - 32*scale) / 2
+	x := (screenWidth - 32*scale) / 2
 	for cy := 0; cy < 8*digitSize; cy++ {
 		for shift := digitSize - 1; shift >= 0; shift-- {
 			row := int(digits[pos+cy*digitSize+shift])
 			for cx := 7; cx >= 0; cx-- {
 				if row%2 != 0 {
-					for dy := 0; dy < digitSize; dy++ {
-						for dx := 0; dx < digitSize; dx++ {
-							SetPixel(screen, x+((7-cx)+shift*8)*digitSize+dx, y+cy*digitSize+dy, 255, 255, 255)
+					for dy := 0; dy < scale; dy++ {
+						for dx := 0; dx < scale; dx++ {
+							c.SetPixel(screen, x+((7-cx)+shift*8)*scale+dx, y+cy*scale+dy, 255, 255, 255)
 						}
 					}
 				}
@@ -1171,7 +1044,18 @@ func DrawDigit(screen int, digit int) {
 	}
 }
 
-func MakeRenderCanvas(screen int) func(*draw2dimg.GraphicContext) bool {
+func (c *Cube) MakeRenderCanvas(screen int) RenderFunc {
+	if c.display.LCD {
+		scale := c.CanvasScale()
+		lcd := image.NewRGBA(image.Rect(0, 0, screenWidth*scale, screenHeight*scale))
+		return func(gc *draw2dimg.GraphicContext) bool {
+			Update(func() {
+				RenderLCD(c.ScreenFrame(screen), lcd, scale, c.display, &c.lcdLUT)
+			})
+			gc.DrawImage(lcd)
+			return true
+		}
+	}
 	return func(gc *draw2dimg.GraphicContext) bool {
 		gc.SetFillColor(color.RGBA{0xff, 0x00, 0xff, 0xff})
 		gc.SetStrokeColor(color.RGBA{0xFF, 0x00, 0x00, 0xFF})
@@ -1180,8 +1064,10 @@ func MakeRenderCanvas(screen int) func(*draw2dimg.GraphicContext) bool {
 		//gc.LineTo(float64(width), float64(height))
 		//gc.MoveTo(float64(width), 0)
 		//gc.LineTo(0, float64(height))
-		img := &image.NRGBA{Pix: screens[screen].points, Rect: image.Rect(0, 0, screenWidth, screenHeight), Stride: screenWidth * 4}
-		gc.DrawImage(img)
+		Update(func() {
+			img := &image.NRGBA{Pix: c.ScreenFrame(screen), Rect: image.Rect(0, 0, screenWidth, screenHeight), Stride: screenWidth * 4}
+			gc.DrawImage(img)
+		})
 		gc.Stroke()
 		gc.Close()
 		return true
@@ -1189,15 +1075,23 @@ func MakeRenderCanvas(screen int) func(*draw2dimg.GraphicContext) bool {
 }
 
 type UpdateInfo struct {
-	Screen   *int   `json:"screen"`
-	IsText   bool   `json:"is_text"`
-	Color    string `json:"color"`
-	Position *int   `json:"position"`
-	Select   *bool  `json:"select"`
+	Screen             *int         `json:"screen"`
+	IsText             bool         `json:"is_text"`
+	Color              string       `json:"color"`
+	Position           *int         `json:"position"`
+	Select             *bool        `json:"select"`
+	Transition         string       `json:"transition"`
+	TransitionDuration *int         `json:"transition_duration"`
+	IsAnimation        bool         `json:"is_animation"`
+	StopAnimation      bool         `json:"stop_animation"`
+	Light              *LightEffect `json:"light"`
+	Sound              *SoundInfo   `json:"sound"`
 }
 
 type CubeInfo struct {
 	Type   int  `json:"type"`
 	Screen *int `json:"screen,omitempty"`
 	State  *int `json:"state,omitempty"`
+	//TYPE_ACCEL при встряхивании: ускорение по осям куба, м/с^2 без силы тяжести
+	Acceleration *[3]float64 `json:"acceleration,omitempty"`
 }
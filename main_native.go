@@ -0,0 +1,13 @@
+//go:build !js
+// +build !js
+
+package main
+
+import "os"
+
+// Эмулятор работает только в браузере (GOOS=js GOARCH=wasm). Вне браузера собирается
+// чистая логика без syscall/js: состояние, порядок пикселей, QR-коды, мелодии - для go test -race.
+func main() {
+	println("The emulator runs in a browser: build it with GOOS=js GOARCH=wasm")
+	os.Exit(1)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type Note struct {
+	Frequency float64 `json:"frequency" example:"440"` //0 - пауза
+	Duration  int     `json:"duration" example:"200"`  //ms
+}
+
+// SoundInfo описывает звук: один тон, последовательность нот или мелодию в формате RTTTL
+type SoundInfo struct {
+	Frequency *float64 `json:"frequency" example:"440"`
+	Duration  int      `json:"duration" example:"200"`
+	Notes     []Note   `json:"notes"`
+	Melody    *string  `json:"melody" example:"beep:d=4,o=5,b=120:c,e,g"`
+	Stop      bool     `json:"stop"`
+}
+
+func (s SoundInfo) ToNotes() ([]Note, error) {
+	if s.Melody != nil {
+		return ParseRTTTL(*s.Melody)
+	}
+	if len(s.Notes) > 0 {
+		return s.Notes, nil
+	}
+	if s.Frequency != nil {
+		return []Note{{Frequency: *s.Frequency, Duration: s.Duration}}, nil
+	}
+	return nil, errors.New("sound is empty")
+}
+
+var rtttlNotes = map[string]int{
+	"c": 0, "c#": 1, "d": 2, "d#": 3, "e": 4, "f": 5, "f#": 6, "g": 7, "g#": 8, "a": 9, "a#": 10, "b": 11, "h": 11,
+}
+
+// ParseRTTTL разбирает мелодию вида "name:d=4,o=5,b=120:8c6,8p,e."
+func ParseRTTTL(melody string) ([]Note, error) {
+	parts := strings.Split(melody, ":")
+	if len(parts) != 3 {
+		return nil, errors.New("melody should contain name, defaults and notes")
+	}
+	defDuration, defOctave, bpm := 4, 6, 63
+	for _, def := range strings.Split(parts[1], ",") {
+		kv := strings.SplitN(strings.TrimSpace(def), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		value, err := strconv.Atoi(kv[1])
+		if err != nil {
+			return nil, err
+		}
+		switch kv[0] {
+		case "d":
+			defDuration = value
+		case "o":
+			defOctave = value
+		case "b":
+			bpm = value
+		}
+	}
+	if bpm <= 0 || defDuration <= 0 {
+		return nil, errors.New("wrong melody defaults")
+	}
+	whole := 60000 * 4 / bpm
+
+	var notes []Note
+	for _, token := range strings.Split(parts[2], ",") {
+		token = strings.ToLower(strings.TrimSpace(token))
+		if token == "" {
+			continue
+		}
+		i := 0
+		for i < len(token) && token[i] >= '0' && token[i] <= '9' {
+			i++
+		}
+		duration := defDuration
+		if i > 0 {
+			duration, _ = strconv.Atoi(token[:i])
+		}
+		if duration <= 0 {
+			return nil, errors.New("wrong note duration " + token)
+		}
+		rest := token[i:]
+		dotted := strings.Contains(rest, ".")
+		rest = strings.Replace(rest, ".", "", -1)
+		if rest == "" {
+			return nil, errors.New("wrong note " + token)
+		}
+		name := rest[:1]
+		rest = rest[1:]
+		if strings.HasPrefix(rest, "#") {
+			name += "#"
+			rest = rest[1:]
+		}
+		octave := defOctave
+		if rest != "" {
+			o, err := strconv.Atoi(rest)
+			if err != nil {
+				return nil, errors.New("wrong note octave " + token)
+			}
+			octave = o
+		}
+		ms := whole / duration
+		if dotted {
+			ms += ms / 2
+		}
+		frequency := 0.0
+		if name != "p" {
+			semitone, ok := rtttlNotes[name]
+			if !ok {
+				return nil, errors.New("wrong note " + token)
+			}
+			frequency = 440 * math.Pow(2, float64(semitone-9)/12+float64(octave-4))
+		}
+		notes = append(notes, Note{Frequency: frequency, Duration: ms})
+	}
+	return notes, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseRTTTL(t *testing.T) {
+	tests := []struct {
+		name   string
+		melody string
+		notes  []Note
+	}{
+		{"defaults d=4 o=6 b=63", "beep::a,p", []Note{{1760, 952}, {0, 952}}},
+		{"partial defaults", "beep:b=120:a,8a", []Note{{1760, 500}, {1760, 250}}},
+		{"default duration", "beep:d=8,o=5,b=120:a,2a", []Note{{880, 250}, {880, 1000}}},
+		{"dotted notes", "beep:d=4,o=5,b=120:8c.,a.,16p.", []Note{{523.25, 375}, {880, 750}, {0, 187}}},
+		{"dot after octave", "beep:d=4,o=5,b=120:8c6.", []Note{{1046.50, 375}}},
+		{"octaves", "beep:d=4,o=5,b=120:a4,a,a6,c#7,h7", []Note{{440, 500}, {880, 500}, {1760, 500}, {2217.46, 500}, {3951.07, 500}}},
+		{"sharps", "beep:d=4,o=4,b=120:c,c#,d#,f#,g#,a#", []Note{{261.63, 500}, {277.18, 500}, {311.13, 500}, {369.99, 500}, {415.30, 500}, {466.16, 500}}},
+		{"pauses", "beep:d=4,o=5,b=120:p,8p,1p,a", []Note{{0, 500}, {0, 250}, {0, 2000}, {880, 500}}},
+		{"spaces and case", "Beep: d=4, o=5, b=120 : 8C, A ,", []Note{{523.25, 250}, {880, 500}}},
+	}
+	for _, test := range tests {
+		notes, err := ParseRTTTL(test.melody)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(notes) != len(test.notes) {
+			t.Errorf("%s: got %d notes %v, want %v", test.name, len(notes), notes, test.notes)
+			continue
+		}
+		for i, n := range notes {
+			want := test.notes[i]
+			if math.Abs(n.Frequency-want.Frequency) > 0.01 || n.Duration != want.Duration {
+				t.Errorf("%s: note %d is %v, want %v", test.name, i, n, want)
+			}
+		}
+	}
+}
+
+func TestParseRTTTLErrors(t *testing.T) {
+	for _, melody := range []string{
+		"",
+		"beep:d=4,o=5,b=120",
+		"beep:d=4:o=5:b=120:a",
+		"beep:d=0,o=5,b=120:a",
+		"beep:d=4,o=5,b=0:a",
+		"beep:d=x:a",
+		"beep:d=4,o=5,b=120:0a",
+		"beep:d=4,o=5,b=120:x",
+		"beep:d=4,o=5,b=120:ax",
+		"beep:d=4,o=5,b=120:8.",
+	} {
+		if notes, err := ParseRTTTL(melody); err == nil {
+			t.Errorf("%q is parsed to %v", melody, notes)
+		}
+	}
+}
+
+func TestSoundInfoToNotes(t *testing.T) {
+	frequency := 440.0
+	melody := "beep:d=4,o=5,b=120:a"
+	tests := []struct {
+		sound SoundInfo
+		notes []Note
+	}{
+		{SoundInfo{Frequency: &frequency, Duration: 200}, []Note{{440, 200}}},
+		{SoundInfo{Notes: []Note{{440, 100}, {0, 50}}}, []Note{{440, 100}, {0, 50}}},
+		{SoundInfo{Melody: &melody, Frequency: &frequency, Duration: 200}, []Note{{880, 500}}},
+	}
+	for _, test := range tests {
+		notes, err := test.sound.ToNotes()
+		if err != nil {
+			t.Errorf("%+v: %v", test.sound, err)
+			continue
+		}
+		if len(notes) != len(test.notes) {
+			t.Errorf("%+v: got %v, want %v", test.sound, notes, test.notes)
+			continue
+		}
+		for i := range notes {
+			if notes[i] != test.notes[i] {
+				t.Errorf("%+v: got %v, want %v", test.sound, notes, test.notes)
+				break
+			}
+		}
+	}
+	if _, err := (SoundInfo{}).ToNotes(); err == nil {
+		t.Error("empty sound is played")
+	}
+}
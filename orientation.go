@@ -0,0 +1,236 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"strconv"
+)
+
+// грани куба: 0..3 - экраны, далее верхняя и нижняя грани
+const FACE_TOP = 4
+const FACE_BOTTOM = 5
+
+const TIP_FORWARD = 0  //от пользователя
+const TIP_BACKWARD = 1 //к пользователю
+const TIP_LEFT = 2
+const TIP_RIGHT = 3
+
+type Vector [3]int
+
+// нормали граней в системе координат куба: x - вправо, y - вверх, z - к пользователю
+var faceNormals = []Vector{
+	{0, 0, 1},
+	{1, 0, 0},
+	{0, 0, -1},
+	{-1, 0, 0},
+	{0, 1, 0},
+	{0, -1, 0},
+}
+
+var faceNames = []string{"1", "2", "3", "4", "top", "bottom"}
+
+// Orientation - поворот куба относительно стола (переводит координаты куба в мировые)
+type Orientation [3][3]int
+
+func IdentityOrientation() Orientation {
+	return Orientation{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+}
+
+func (o Orientation) Apply(v Vector) Vector {
+	var r Vector
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			r[i] += o[i][j] * v[j]
+		}
+	}
+	return r
+}
+
+// Rotate поворачивает куб на 90 градусов вокруг мировой оси (0 - x, 1 - y, 2 - z), quarter - число четвертей
+func (o Orientation) Rotate(axis int, quarter int) Orientation {
+	quarter = (quarter%4 + 4) % 4
+	for q := 0; q < quarter; q++ {
+		a := (axis + 1) % 3
+		b := (axis + 2) % 3
+		var r Orientation
+		for j := 0; j < 3; j++ {
+			r[axis][j] = o[axis][j]
+			r[a][j] = -o[b][j]
+			r[b][j] = o[a][j]
+		}
+		o = r
+	}
+	return o
+}
+
+// FaceTowards возвращает грань, нормаль которой смотрит в направлении dir
+func (o Orientation) FaceTowards(dir Vector) int {
+	for f, n := range faceNormals {
+		if o.Apply(n) == dir {
+			return f
+		}
+	}
+	return -1
+}
+
+func (o Orientation) Up() int {
+	return o.FaceTowards(Vector{0, 1, 0})
+}
+
+func (o Orientation) Front() int {
+	return o.FaceTowards(Vector{0, 0, 1})
+}
+
+func negate(a Vector) Vector {
+	return Vector{-a[0], -a[1], -a[2]}
+}
+
+// ScreenRotation - угол (0 или 180), на который прошивка поворачивает изображение экрана.
+// Прошивка умеет только переворачивать изображение, поэтому экран, лежащий на боку
+// (верх куба смотрит вправо или влево), выводится без поворота.
+func (o Orientation) ScreenRotation(screen int) int {
+	n := o.Apply(faceNormals[screen])
+	u := o.Apply(faceNormals[FACE_TOP])
+	viewUp := Vector{0, 1, 0}
+	if n[1] > 0 {
+		//экран смотрит вверх - пользователь смотрит на него сверху
+		viewUp = Vector{0, 0, -1}
+	} else if n[1] < 0 {
+		viewUp = Vector{0, 0, 1}
+	}
+	if u == negate(viewUp) {
+		return 180
+	}
+	return 0
+}
+
+func (c *Cube) Flipped() bool {
+	return c.orientation.Up() == FACE_BOTTOM
+}
+
+// SetOrientation пересчитывает повороты экранов и отправляет события акселерометра
+func (c *Cube) SetOrientation(o Orientation) {
+	wasFlipped := c.Flipped()
+	previousUp := c.orientation.Up()
+	c.orientation = o
+	c.UpdateRotations()
+	if wasFlipped != c.Flipped() {
+		//как и раньше, передается состояние до переворота
+		fd := 0
+		if wasFlipped {
+			fd = 1
+		}
+		data, _ := json.Marshal(CubeInfo{Type: TYPE_FLIP, State: &fd})
+		c.SendToServer(string(data))
+	}
+	if previousUp != o.Up() {
+		up := o.Up()
+		data, _ := json.Marshal(CubeInfo{Type: TYPE_ACCEL, State: &up})
+		c.SendToServer(string(data))
+	}
+	c.UpdateScreens()
+	vecty.Rerender(emulator)
+}
+
+// UpdateRotations определяет, какие экраны прошивка выводит перевернутыми (поворот 180)
+// Модель положения рассчитана на 4 боковые грани; дополнительные экраны других ревизий
+// только переворачиваются вместе с кубом
+func (c *Cube) UpdateRotations() {
+	for i := range c.rotations {
+		if i < FACE_TOP {
+			c.rotations[i] = c.orientation.ScreenRotation(i)
+		} else if c.Flipped() {
+			c.rotations[i] = 180
+		} else {
+			c.rotations[i] = 0
+		}
+	}
+}
+
+func (c *Cube) Tip(direction int) {
+	switch direction {
+	case TIP_FORWARD:
+		c.SetOrientation(c.orientation.Rotate(0, -1))
+	case TIP_BACKWARD:
+		c.SetOrientation(c.orientation.Rotate(0, 1))
+	case TIP_LEFT:
+		c.SetOrientation(c.orientation.Rotate(2, 1))
+	case TIP_RIGHT:
+		c.SetOrientation(c.orientation.Rotate(2, -1))
+	}
+}
+
+// Flip переворачивает куб вверх дном, не меняя грань, обращенную к пользователю
+func (c *Cube) Flip() {
+	c.SetOrientation(c.orientation.Rotate(2, 2))
+}
+
+// FaceToUser поворачивает куб вокруг вертикали так, чтобы экран смотрел на пользователя
+func (c *Cube) FaceToUser(screen int) {
+	if screen >= FACE_TOP || c.orientation.Apply(faceNormals[screen])[1] != 0 {
+		return
+	}
+	o := c.orientation
+	for o.Front() != screen {
+		o = o.Rotate(1, 1)
+	}
+	if o == c.orientation {
+		return
+	}
+	//верх не меняется, событий акселерометра нет, но экраны сверху поворачиваются вместе с кубом
+	c.orientation = o
+	c.UpdateRotations()
+	c.UpdateScreens()
+}
+
+// CSSMatrix переводит поворот в matrix3d (в CSS ось y направлена вниз)
+func (o Orientation) CSSMatrix() string {
+	sign := [3]int{1, -1, 1}
+	m := ""
+	for j := 0; j < 4; j++ {
+		for i := 0; i < 4; i++ {
+			v := 0
+			if i < 3 && j < 3 {
+				v = sign[i] * o[i][j] * sign[j]
+			} else if i == j {
+				v = 1
+			}
+			if m != "" {
+				m += ","
+			}
+			m += strconv.Itoa(v)
+		}
+	}
+	return "matrix3d(" + m + ")"
+}
+
+type TiltPanel struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *TiltPanel) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	tip := func(direction int, ch string) *vecty.HTML {
+		return elem.Span(vecty.Markup(
+			vecty.Class("tilt-button"),
+			On("click", func(event *vecty.Event) {
+				if c.powerOn {
+					c.Tip(direction)
+				}
+			}),
+		), vecty.Text(ch))
+	}
+	return elem.Div(vecty.Markup(vecty.Class("tilt")),
+		tip(TIP_LEFT, "\uF060"),
+		tip(TIP_FORWARD, "\uF062"),
+		elem.Span(vecty.Markup(vecty.Class("tilt-state")),
+			vecty.Text(faceNames[c.orientation.Up()]+" / "+faceNames[c.orientation.Front()])),
+		tip(TIP_BACKWARD, "\uF063"),
+		tip(TIP_RIGHT, "\uF061"),
+	)
+}
@@ -0,0 +1,105 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"github.com/hexops/vecty"
+	"strconv"
+	"syscall/js"
+	"time"
+)
+
+const swipeDistance = 40 //px
+
+// Press - обработчики нажатия кнопки мышью, пальцем или пером.
+// Указатель захватывается, поэтому отпускание засчитывается, даже если палец ушел с кнопки;
+// release получает число пройденных порогов удержания
+func (c *Cube) Press(name string, thresholds []int, release func(level int)) vecty.MarkupList {
+	g := c.Gesture(name)
+	return vecty.Markup(
+		On("pointerdown", func(event *vecty.Event) {
+			target := event.Get("currentTarget")
+			target.Call("setPointerCapture", event.Get("pointerId"))
+			target.Get("classList").Call("add", "pressed")
+			Vibrate(10)
+			g.Begin(thresholds, time.Now())
+			go ShowHoldProgress(g, target)
+		}),
+		On("pointerup", func(event *vecty.Event) {
+			level, ok := g.End(time.Now())
+			if ok {
+				release(level)
+			}
+		}),
+		On("pointercancel", func(event *vecty.Event) {
+			g.Cancel()
+		}),
+		//долгое нажатие на телефоне не должно открывать контекстное меню
+		(On("contextmenu", func(event *vecty.Event) {})).PreventDefault(),
+	)
+}
+
+// ShowHoldProgress показывает на кнопке, какой жест сработает при отпускании
+func ShowHoldProgress(g *GestureRecognizer, target js.Value) {
+	classList := target.Get("classList")
+	style := target.Get("style")
+	shown := 0
+	pressed := true
+	for pressed {
+		Update(func() {
+			if pressed = g.Pressed(); !pressed {
+				return
+			}
+			now := time.Now()
+			style.Call("setProperty", "--hold", strconv.FormatFloat(g.Progress(now), 'f', 3, 64))
+			if level := g.Level(now); level != shown {
+				classList.Call("remove", "hold-"+strconv.Itoa(shown))
+				classList.Call("add", "hold-"+strconv.Itoa(level))
+				shown = level
+				Vibrate(20)
+			}
+		})
+		if pressed {
+			time.Sleep(40 * time.Millisecond)
+		}
+	}
+	classList.Call("remove", "pressed", "hold-"+strconv.Itoa(shown))
+	style.Call("removeProperty", "--hold")
+}
+
+// Swipe - смена экрана движением пальца по экранам
+func (c *Cube) Swipe() vecty.MarkupList {
+	return vecty.Markup(
+		On("pointerdown", func(event *vecty.Event) {
+			if event.Get("pointerType").String() == "mouse" {
+				return
+			}
+			c.swipeX = event.Get("clientX").Float()
+			c.swiping = true
+		}),
+		On("pointerup", func(event *vecty.Event) {
+			if !c.swiping {
+				return
+			}
+			c.swiping = false
+			dx := event.Get("clientX").Float() - c.swipeX
+			if dx <= -swipeDistance {
+				c.Right()
+			} else if dx >= swipeDistance {
+				c.Left()
+			}
+		}),
+		On("pointercancel", func(event *vecty.Event) {
+			c.swiping = false
+		}),
+	)
+}
+
+// Vibrate - короткая вибрация там, где она поддерживается
+func Vibrate(ms int) {
+	navigator := js.Global().Get("navigator")
+	if navigator.Get("vibrate").Truthy() {
+		navigator.Call("vibrate", ms)
+	}
+}
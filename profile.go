@@ -0,0 +1,77 @@
+package main
+
+const PIXEL_RGB565 = "rgb565" //2 байта, little endian
+const PIXEL_RGB888 = "rgb888" //3 байта: r, g, b
+
+// DeviceProfile описывает аппаратную ревизию куба
+type DeviceProfile struct {
+	Name        string   `json:"name"`
+	Width       int      `json:"width"`
+	Height      int      `json:"height"`
+	Faces       int      `json:"faces"`
+	PixelFormat string   `json:"pixel_format"`
+	Scan        ScanMode `json:"scan"` //порядок пикселей по умолчанию
+}
+
+var profiles = map[string]DeviceProfile{
+	"aircube": {Name: "aircube", Width: 160, Height: 128, Faces: 4, PixelFormat: PIXEL_RGB565,
+		Scan: ScanMode{Order: SCAN_COLUMNS, Rotation: 180, Mirror: true}},
+}
+
+const defaultProfile = "aircube"
+
+var profile = profiles[defaultProfile]
+
+// размеры экранов и число граней выбранного профиля
+var screenWidth = profile.Width
+var screenHeight = profile.Height
+var faceCount = profile.Faces
+
+// SetProfile должен вызываться до создания кубов
+func SetProfile(p DeviceProfile) {
+	profile = p
+	screenWidth = p.Width
+	screenHeight = p.Height
+	faceCount = p.Faces
+	println("Device profile ", p.Name, p.Width, "x", p.Height, p.Faces, p.PixelFormat, p.Scan.String())
+}
+
+func (p DeviceProfile) BytesPerPixel() int {
+	if p.PixelFormat == PIXEL_RGB888 {
+		return 3
+	}
+	return 2
+}
+
+// DecodePixel читает i-й пиксель изображения; ok = false, если данных не хватает
+func (p DeviceProfile) DecodePixel(img []byte, i int) (r, g, b byte, ok bool) {
+	if p.PixelFormat == PIXEL_RGB888 {
+		if i*3+2 >= len(img) {
+			return 0, 0, 0, false
+		}
+		return img[i*3], img[i*3+1], img[i*3+2], true
+	}
+	if i*2+1 >= len(img) {
+		return 0, 0, 0, false
+	}
+	point := uint16(img[i*2+1])<<8 + uint16(img[i*2])
+	return byte((point >> 11) % 32 << 3), byte((point >> 5) % 64 << 2), byte(point % 32 << 3), true
+}
+
+// DecodeScreenImage переводит изображение в формате экрана в RGBA по строкам
+func DecodeScreenImage(img []byte, mode ScanMode) []byte {
+	frame := make([]byte, screenWidth*screenHeight*4)
+	for i := 0; i < screenWidth*screenHeight; i++ {
+		r, g, b, ok := profile.DecodePixel(img, i)
+		if !ok {
+			break
+		}
+		x, y := mode.Position(i, screenWidth, screenHeight)
+		pos := (y*screenWidth + x) * 4
+		frame[pos] = r
+		frame[pos+1] = g
+		frame[pos+2] = b
+		frame[pos+3] = 255
+	}
+	return frame
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"syscall/js"
+)
+
+// LoadProfile выбирает профиль: параметр страницы ?profile=имя, затем настройка "profile"
+// (имя встроенного профиля или JSON с описанием своего)
+func LoadProfile() {
+	name := js.Global().Get("URLSearchParams").New(js.Global().Get("location").Get("search")).Call("get", "profile")
+	if !name.IsNull() {
+		if p, ok := profiles[name.String()]; ok {
+			SetProfile(p)
+			return
+		}
+		println("Unknown profile ", name.String())
+	}
+	stored := storage.Get("profile")
+	if stored == nil {
+		return
+	}
+	if p, ok := profiles[*stored]; ok {
+		SetProfile(p)
+		return
+	}
+	p := profiles[defaultProfile]
+	if err := json.Unmarshal([]byte(*stored), &p); err != nil || p.Width <= 0 || p.Height <= 0 || p.Faces <= 0 {
+		println("Profile is broken ", *stored)
+		return
+	}
+	SetProfile(p)
+}
@@ -0,0 +1,428 @@
+package main
+
+import "errors"
+
+// Кодировщик QR-кодов (байтовый режим, версии 1-10, уровни коррекции L и M)
+
+const QR_L = 1
+const QR_M = 0
+
+type qrBlocks struct {
+	ec     int      //кодовых слов коррекции на блок
+	groups [][2]int //{число блоков, кодовых слов данных в блоке}
+}
+
+var qrTable = map[int][11]qrBlocks{
+	QR_L: {
+		{},
+		{7, [][2]int{{1, 19}}},
+		{10, [][2]int{{1, 34}}},
+		{15, [][2]int{{1, 55}}},
+		{20, [][2]int{{1, 80}}},
+		{26, [][2]int{{1, 108}}},
+		{18, [][2]int{{2, 68}}},
+		{20, [][2]int{{2, 78}}},
+		{24, [][2]int{{2, 97}}},
+		{30, [][2]int{{2, 116}}},
+		{18, [][2]int{{2, 68}, {2, 69}}},
+	},
+	QR_M: {
+		{},
+		{10, [][2]int{{1, 16}}},
+		{16, [][2]int{{1, 28}}},
+		{26, [][2]int{{1, 44}}},
+		{18, [][2]int{{2, 32}}},
+		{24, [][2]int{{2, 43}}},
+		{16, [][2]int{{4, 27}}},
+		{18, [][2]int{{4, 31}}},
+		{22, [][2]int{{2, 38}, {2, 39}}},
+		{22, [][2]int{{3, 36}, {2, 37}}},
+		{26, [][2]int{{4, 43}, {1, 44}}},
+	},
+}
+
+var qrAlignment = [11][]int{
+	nil, nil,
+	{6, 18}, {6, 22}, {6, 26}, {6, 30}, {6, 34},
+	{6, 22, 38}, {6, 24, 42}, {6, 26, 46}, {6, 28, 50},
+}
+
+// QRCode - матрица модулей, true - темный
+type QRCode struct {
+	Size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func (q *QRCode) Dark(x, y int) bool {
+	return q.modules[y][x]
+}
+
+func (b qrBlocks) dataCodewords() int {
+	n := 0
+	for _, g := range b.groups {
+		n += g[0] * g[1]
+	}
+	return n
+}
+
+// EncodeQR кодирует данные в QR-код наименьшей подходящей версии
+func EncodeQR(data []byte, level int) (*QRCode, error) {
+	for version := 1; version <= 10; version++ {
+		blocks := qrTable[level][version]
+		countBits := 8
+		if version >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+len(data)*8 > blocks.dataCodewords()*8 {
+			continue
+		}
+		codewords := qrAddErrorCorrection(qrDataCodewords(data, countBits, blocks.dataCodewords()), blocks)
+		return qrBuild(version, level, codewords), nil
+	}
+	return nil, errors.New("data is too long for QR code")
+}
+
+// qrDataCodewords - режим, длина, данные, терминатор и заполнение
+func qrDataCodewords(data []byte, countBits int, capacity int) []byte {
+	var bits []bool
+	put := func(value int, n int) {
+		for i := n - 1; i >= 0; i-- {
+			bits = append(bits, (value>>uint(i))&1 != 0)
+		}
+	}
+	put(4, 4) //байтовый режим
+	put(len(data), countBits)
+	for _, b := range data {
+		put(int(b), 8)
+	}
+	for i := 0; i < 4 && len(bits) < capacity*8; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+	result := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << uint(7-j)
+			}
+		}
+		result = append(result, b)
+	}
+	for pad := byte(0xEC); len(result) < capacity; pad ^= 0xEC ^ 0x11 {
+		result = append(result, pad)
+	}
+	return result
+}
+
+// qrAddErrorCorrection делит данные на блоки, добавляет коды Рида-Соломона и чередует блоки
+func qrAddErrorCorrection(data []byte, blocks qrBlocks) []byte {
+	var dataBlocks, ecBlocks [][]byte
+	pos := 0
+	for _, g := range blocks.groups {
+		for i := 0; i < g[0]; i++ {
+			block := data[pos : pos+g[1]]
+			pos += g[1]
+			dataBlocks = append(dataBlocks, block)
+			ecBlocks = append(ecBlocks, reedSolomon(block, blocks.ec))
+		}
+	}
+	var result []byte
+	for i := 0; ; i++ {
+		added := false
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	for i := 0; i < blocks.ec; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// арифметика в GF(256) с многочленом x^8+x^4+x^3+x^2+1
+func gfMultiply(x, y byte) byte {
+	var z byte
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x1D)
+		z ^= ((y >> uint(i)) & 1) * x
+	}
+	return z
+}
+
+func reedSolomon(data []byte, degree int) []byte {
+	//порождающий многочлен (x - a^0)(x - a^1)...(x - a^(degree-1)), старший коэффициент опущен
+	generator := make([]byte, degree)
+	generator[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			generator[j] = gfMultiply(generator[j], root)
+			if j+1 < degree {
+				generator[j] ^= generator[j+1]
+			}
+		}
+		root = gfMultiply(root, 0x02)
+	}
+	result := make([]byte, degree)
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[degree-1] = 0
+		for i := range result {
+			result[i] ^= gfMultiply(generator[i], factor)
+		}
+	}
+	return result
+}
+
+func qrBuild(version int, level int, codewords []byte) *QRCode {
+	size := version*4 + 17
+	q := &QRCode{Size: size}
+	q.modules = make([][]bool, size)
+	q.function = make([][]bool, size)
+	for i := range q.modules {
+		q.modules[i] = make([]bool, size)
+		q.function[i] = make([]bool, size)
+	}
+	q.drawFunctionPatterns(version)
+	q.drawCodewords(codewords)
+	best, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		q.applyMask(mask)
+		q.drawFormatBits(level, mask)
+		if p := q.penalty(); bestPenalty < 0 || p < bestPenalty {
+			best, bestPenalty = mask, p
+		}
+		q.applyMask(mask)
+	}
+	q.applyMask(best)
+	q.drawFormatBits(level, best)
+	return q
+}
+
+func (q *QRCode) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+func (q *QRCode) drawFunctionPatterns(version int) {
+	size := q.Size
+	for i := 0; i < size; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+	for _, c := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x < 0 || y < 0 || x >= size || y >= size {
+					continue
+				}
+				d := qrMax(qrAbs(dx), qrAbs(dy))
+				q.set(x, y, d != 2 && d != 4)
+			}
+		}
+	}
+	positions := qrAlignment[version]
+	last := len(positions) - 1
+	for i, cy := range positions {
+		for j, cx := range positions {
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					q.set(cx+dx, cy+dy, qrMax(qrAbs(dx), qrAbs(dy)) != 1)
+				}
+			}
+		}
+	}
+	//место под формат и темный модуль
+	q.drawFormatBits(0, 0)
+	if version >= 7 {
+		rem := version
+		for i := 0; i < 12; i++ {
+			rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+		}
+		bits := version<<12 | rem
+		for i := 0; i < 18; i++ {
+			dark := (bits>>uint(i))&1 != 0
+			a, b := size-11+i%3, i/3
+			q.set(a, b, dark)
+			q.set(b, a, dark)
+		}
+	}
+}
+
+func (q *QRCode) drawFormatBits(level int, mask int) {
+	data := level<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 != 0 }
+	size := q.Size
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+	for i := 0; i < 8; i++ {
+		q.set(size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, size-15+i, bit(i))
+	}
+	q.set(8, size-8, true)
+}
+
+// drawCodewords укладывает данные змейкой по парам столбцов снизу вверх и обратно
+func (q *QRCode) drawCodewords(codewords []byte) {
+	size := q.Size
+	i := 0
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = size - 1 - vert
+				}
+				if !q.function[y][x] && i < len(codewords)*8 {
+					q.modules[y][x] = (codewords[i>>3]>>uint(7-i&7))&1 != 0
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (q *QRCode) applyMask(mask int) {
+	for y := 0; y < q.Size; y++ {
+		for x := 0; x < q.Size; x++ {
+			if q.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty - штраф маски по четырем правилам стандарта
+func (q *QRCode) penalty() int {
+	size := q.Size
+	result := 0
+	finder := []bool{true, false, true, true, true, false, true}
+	line := func(get func(i int) bool) {
+		run := 1
+		for i := 1; i <= size; i++ {
+			if i < size && get(i) == get(i-1) {
+				run++
+				continue
+			}
+			if run >= 5 {
+				result += 3 + run - 5
+			}
+			run = 1
+		}
+		for i := 0; i+7 <= size; i++ {
+			match := true
+			for k, f := range finder {
+				if get(i+k) != f {
+					match = false
+					break
+				}
+			}
+			if !match {
+				continue
+			}
+			light := func(from, to int) bool {
+				for k := from; k < to; k++ {
+					if k >= 0 && k < size && get(k) {
+						return false
+					}
+				}
+				return true
+			}
+			if light(i-4, i) || light(i+7, i+11) {
+				result += 40
+			}
+		}
+	}
+	dark := 0
+	for y := 0; y < size; y++ {
+		row := y
+		line(func(i int) bool { return q.modules[row][i] })
+		line(func(i int) bool { return q.modules[i][row] })
+		for x := 0; x < size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				c := q.modules[y][x]
+				if q.modules[y][x+1] == c && q.modules[y+1][x] == c && q.modules[y+1][x+1] == c {
+					result += 3
+				}
+			}
+		}
+	}
+	total := size * size
+	k := (qrAbs(dark*20-total*10)+total-1)/total - 1
+	result += k * 10
+	return result
+}
+
+func qrAbs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func qrMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+// Эталонные матрицы получены независимым кодировщиком (QRCode for JavaScript, Kazuhiko Arase)
+// для тех же данных и уровня коррекции с маской, которую выбирает EncodeQR. # - темный модуль.
+var qrReference = []struct {
+	data    string
+	level   int
+	version int
+	modules []string
+}{
+	{
+		"AirCube",
+		QR_M, 1,
+		[]string{
+			"#######.##....#######",
+			"#.....#....#..#.....#",
+			"#.###.#...#.#.#.###.#",
+			"#.###.#.##.#..#.###.#",
+			"#.###.#.#...#.#.###.#",
+			"#.....#.####..#.....#",
+			"#######.#.#.#.#######",
+			"........#.###........",
+			"#...#.###..#.#####..#",
+			"##.##....####......#.",
+			".######.##.#..###..#.",
+			".#.###...##..##.#..#.",
+			"#.#.###...#.####.#.##",
+			"........#...###.#.###",
+			"#######.##..##..####.",
+			"#.....#..####..#...##",
+			"#.###.#.##.#..#...#.#",
+			"#.###.#...###...#.###",
+			"#.###.#....#..#.#.#..",
+			"#.....#......##.#....",
+			"#######.##..####.#..#",
+		},
+	},
+	{
+		"https://aircube.tech/pair?nonce=0123456789abcdef&pin=123456",
+		QR_M, 4,
+		[]string{
+			"#######....##...##.###.#..#######",
+			"#.....#..#.##..#....#.....#.....#",
+			"#.###.#.####..#.#..####...#.###.#",
+			"#.###.#.#..##....####.#.#.#.###.#",
+			"#.###.#.##....###..#.#.#..#.###.#",
+			"#.....#.##..##....#.....#.#.....#",
+			"#######.#.#.#.#.#.#.#.#.#.#######",
+			"........#.##..#.#.####.##........",
+			"#.#####..##..##..#.#..#...#####..",
+			"###.#...#..###..#.##.#.#..##.####",
+			".#....#..##.#####.#..##..#..#.#..",
+			"#.#.........##..#....#.##.#.###..",
+			"..##.##..#..#.#..###..#.#...##...",
+			"....#..##......#...###.#.###..###",
+			".###..####.##.##..#.###..##..###.",
+			".......#.#..#...#....##.###...#..",
+			"......#....#.#.#.#.#....##.###..#",
+			"##..##.#.#.#..#.#.####.#..##.####",
+			"##.####...##..##.##.###.##.##.#..",
+			".##.##...#..##..#.##.###########.",
+			"####..#....#....###...#.#...##.##",
+			"##...#..##.##.#..#.##..#.###..#.#",
+			"#.#...#.#....#.##.#...#....#...#.",
+			"#.#.#..###.#.####....#..##....###",
+			"#.#...#.#.####.#.#....########..#",
+			"........###..#..#..######...#.###",
+			"#######....#.#.####.#.###.#.#.#..",
+			"#.....#.#.####..#...##.##...####.",
+			"#.###.#.#..##...###.#...######.#.",
+			"#.###.#.#....#.....###.###..#.###",
+			"#.###.#.###.#.##.##..##...##.#...",
+			"#.....#...#.#.###.####.#..#.#.#..",
+			"#######.#...##.####...#.#..#.#.#.",
+		},
+	},
+	{
+		"AIRCUBE-PAIRING-0123456789-abcdefghijklmnopqrstuvwxyz-ABCDEFGHIJKLMNOPQRSTUVWXYZ-AIRCUBE-PAIRING-0123456789-abcdefghijklmnopqrstuvwxyz-ABCDE",
+		QR_L, 7,
+		[]string{
+			"#######.....##.#..##.#.#.#...##.#...#.#######",
+			"#.....#.##.####..###..###.#.#......#..#.....#",
+			"#.###.#...##.###..###.....###..###.#..#.###.#",
+			"#.###.#.#.#..#.#.##...###.#..##..#.##.#.###.#",
+			"#.###.#..##..#....########....#...###.#.###.#",
+			"#.....#.#..#.####.#.#...#.###...##....#.....#",
+			"#######.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#.#######",
+			".........###..#.#####...#####.#.##.##........",
+			"#####.###.#..###.########.#..#.#.###.#.#.#.#.",
+			"...##..#..###...#.#....#.#.#.###.#..##...#...",
+			".#...####..##..####.#.###.#.#.....####.#####.",
+			"#.##.#.#.####...###.......####.##.#.#######.#",
+			"#..#..#...#..#.#.##.#.#.###..##.....##.#..#..",
+			".....#.##.##.#....#.#..#.#.#.#####.###....#.#",
+			"..#.#####.#.......#..#.##.###..#..#..#...#...",
+			".#.###.#..##.#..#..#...########.##.#...#.##.#",
+			"##.#.###.#.##....##.#.#####...##..##..#..##.#",
+			"#.#.#..####.##.#..#..###.#..####.#..##....##.",
+			"..###.#.#####....#####.######..##.#.#..#.###.",
+			".#.#...##.####.#...#...######.####..#..#.###.",
+			".#.#######.###.#.########....##..#..#####.#.#",
+			"..#.#...#.#..#....#.#...##..###.##..#...####.",
+			"###.#.#.##.#####..#.#.#.#.#....#..###.#.####.",
+			"#..##...#...#.#.##.##...#..####.#...#...####.",
+			"##..#####.######.##.#####....##..#.######.#.#",
+			".#..#....##.#...#.#.#.#.##.#####.#..#........",
+			"###.#.#..####..####.#..#.####...#.##.#.#####.",
+			".#.#........#.#.##.....######.####.##..#.###.",
+			"#.#..##.##.###.#.##.#...##............##.##..",
+			"...#.#.#######....###.####.#####.#...#....##.",
+			".#.##.#####.....###..###..#....#..####.#...#.",
+			".##.......####.##.#....##..######......#.##..",
+			"..##.###.##.#....###....##..........#..#..#.#",
+			"###......###.#.#..#.#.#.##...##..#.###....#..",
+			"....#.#.#...........#..#.####...#.#.##.....#.",
+			".####...#..#.###..#.#########.####.##....##..",
+			"#..##.#..#.###.#.########.#..#...##.#####.###",
+			"........######....###...##...##..#.##...####.",
+			"#######.#############.#.#.#....#..###.#.####.",
+			"#.....#..####.#######...#..######..##...#.###",
+			"#.###.#.##.#####..#.#####.#..#...##.#####..#.",
+			"#.###.#.#.#.#...#.###..#.#.#.#####.####...#..",
+			"#.###.#.######.##..#..#.#####..#..###.#..#..#",
+			"#.....#.#.#.#...###.####.#########.###...##..",
+			"#######.#..###.#.##..######...#...#.....####.",
+		},
+	},
+}
+
+func TestEncodeQRReference(t *testing.T) {
+	for _, test := range qrReference {
+		q, err := EncodeQR([]byte(test.data), test.level)
+		if err != nil {
+			t.Errorf("%q: %v", test.data, err)
+			continue
+		}
+		if q.Size != test.version*4+17 || q.Size != len(test.modules) {
+			t.Errorf("%q: size %d, want version %d (%d modules)", test.data, q.Size, test.version, len(test.modules))
+			continue
+		}
+		for y, row := range test.modules {
+			for x := range row {
+				if q.Dark(x, y) != (row[x] == '#') {
+					t.Errorf("%q: module %d,%d differs from the reference", test.data, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeQRVersion(t *testing.T) {
+	//наибольшая длина данных в байтовом режиме для версий 1-10
+	capacity := map[int][]int{
+		QR_L: {17, 32, 53, 78, 106, 134, 154, 192, 230, 271},
+		QR_M: {14, 26, 42, 62, 84, 106, 122, 152, 180, 213},
+	}
+	for level, lengths := range capacity {
+		for i, n := range lengths {
+			for _, test := range []struct{ length, version int }{{n, i + 1}, {n + 1, i + 2}} {
+				q, err := EncodeQR(bytes.Repeat([]byte("a"), test.length), level)
+				if test.version > 10 {
+					if err == nil {
+						t.Errorf("level %d: %d bytes are encoded", level, test.length)
+					}
+					continue
+				}
+				if err != nil {
+					t.Errorf("level %d: %d bytes: %v", level, test.length, err)
+					continue
+				}
+				if q.Size != test.version*4+17 {
+					t.Errorf("level %d: %d bytes are encoded in %dx%d, want version %d", level, test.length, q.Size, q.Size, test.version)
+				}
+			}
+		}
+	}
+}
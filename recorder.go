@@ -0,0 +1,186 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"bytes"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"github.com/hexops/vecty/prop"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"strconv"
+	"time"
+)
+
+const recordInterval = 40 * time.Millisecond
+const recordMaxFrames = 1500
+const lightStripHeight = 12 //px
+
+const RECORD_ALL_FACES = -1
+
+// Recorder пишет кадры при каждом изменении экранов и собирает из них gif
+type Recorder struct {
+	cube     *Cube
+	face     int
+	frames   []*image.Paletted
+	delays   []int //сотые доли секунды
+	last     []byte
+	captured time.Time
+	stop     chan bool
+	done     chan bool
+}
+
+// WithLightStrip добавляет под изображением полосу цвета нижней подсветки
+func WithLightStrip(src *image.RGBA, light color.Color) *image.RGBA {
+	b := src.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()+lightStripHeight))
+	draw.Draw(img, b, src, b.Min, draw.Src)
+	draw.Draw(img, image.Rect(0, b.Dy(), b.Dx(), b.Dy()+lightStripHeight), image.NewUniform(light), image.Point{}, draw.Src)
+	return img
+}
+
+// RecordFrame - кадр записи: одна грань или все грани, с полосой подсветки
+func (c *Cube) RecordFrame(face int) *image.RGBA {
+	var img *image.RGBA
+	if face == RECORD_ALL_FACES {
+		img = c.CompositeFrame()
+	} else {
+		img = c.Frame(face)
+	}
+	rgb := c.lightColor.ToRGB()
+	return WithLightStrip(img, color.RGBA{rgb.R, rgb.G, rgb.B, 255})
+}
+
+func (c *Cube) StartRecording(face int) {
+	if c.recorder != nil {
+		return
+	}
+	c.recorder = &Recorder{cube: c, face: face, stop: make(chan bool, 1), done: make(chan bool, 1)}
+	go c.recorder.run()
+	vecty.Rerender(emulator)
+}
+
+// StopRecording останавливает запись и отдает gif браузеру
+func (c *Cube) StopRecording() {
+	r := c.recorder
+	if r == nil {
+		return
+	}
+	c.recorder = nil
+	vecty.Rerender(emulator)
+	go func() {
+		r.stop <- true
+		<-r.done
+		data, err := r.Encode()
+		if err != nil {
+			println("GIF can't be encoded ", err.Error())
+			return
+		}
+		name := "cube" + strconv.Itoa(c.id) + "-" + time.Now().Format("20060102-150405") + ".gif"
+		Download(data, "image/gif", name)
+	}()
+}
+
+func (r *Recorder) run() {
+	defer func() { r.done <- true }()
+	for {
+		Update(func() {
+			r.sample(time.Now())
+		})
+		if len(r.frames) >= recordMaxFrames {
+			println("Recording is too long, stopped")
+			r.finish(time.Now())
+			Update(func() {
+				//как при нажатии кнопки: панель выходит из режима записи, gif сохраняется
+				if r.cube.recorder == r {
+					r.cube.StopRecording()
+				}
+			})
+			return
+		}
+		select {
+		case <-r.stop:
+			r.finish(time.Now())
+			return
+		case <-time.After(recordInterval):
+		}
+	}
+}
+
+// sample добавляет кадр, только если изображение изменилось
+func (r *Recorder) sample(t time.Time) {
+	img := r.cube.RecordFrame(r.face)
+	if r.last != nil && bytes.Equal(r.last, img.Pix) {
+		return
+	}
+	r.finish(t)
+	r.last = img.Pix
+	r.captured = t
+	paletted := image.NewPaletted(img.Bounds(), palette.Plan9)
+	draw.Draw(paletted, img.Bounds(), img, image.Point{}, draw.Src)
+	r.frames = append(r.frames, paletted)
+	r.delays = append(r.delays, 0)
+}
+
+// finish задает длительность последнего кадра
+func (r *Recorder) finish(t time.Time) {
+	if len(r.delays) == 0 {
+		return
+	}
+	delay := int(t.Sub(r.captured).Milliseconds() / 10)
+	if delay < 2 {
+		//браузеры показывают более короткие кадры медленнее
+		delay = 2
+	}
+	r.delays[len(r.delays)-1] = delay
+}
+
+func (r *Recorder) Encode() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gif.EncodeAll(&buf, &gif.GIF{Image: r.frames, Delay: r.delays})
+	return buf.Bytes(), err
+}
+
+type RecorderPanel struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *RecorderPanel) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	options := vecty.List{elem.Option(vecty.Markup(
+		prop.Value(strconv.Itoa(RECORD_ALL_FACES)),
+		vecty.Property("selected", c.recordFace == RECORD_ALL_FACES),
+	), vecty.Text("all"))}
+	for i := range c.screens {
+		options = append(options, elem.Option(vecty.Markup(
+			prop.Value(strconv.Itoa(i)),
+			vecty.Property("selected", c.recordFace == i),
+		), vecty.Text(strconv.Itoa(i+1))))
+	}
+	return elem.Span(vecty.Markup(vecty.Class("recorder")),
+		elem.Span(vecty.Markup(
+			vecty.Class("record-button"),
+			vecty.MarkupIf(c.recorder != nil, vecty.Class("on")),
+			vecty.Property("title", "record gif"),
+			On("click", func(event *vecty.Event) {
+				if c.recorder != nil {
+					c.StopRecording()
+				} else {
+					c.StartRecording(c.recordFace)
+				}
+			}),
+		), vecty.Text("\uF03D")),
+		elem.Select(vecty.Markup(
+			vecty.Property("disabled", c.recorder != nil),
+			On("change", func(e *vecty.Event) {
+				c.recordFace, _ = strconv.Atoi(e.Target.Get("value").String())
+			}),
+		), options),
+	)
+}
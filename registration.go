@@ -0,0 +1,350 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"math/big"
+	"net/url"
+	"strings"
+	"syscall/js"
+	"time"
+)
+
+// состояния привязки куба к аккаунту
+const REG_IDLE = 0
+const REG_WAITING = 1 //PIN на экранах, ждем привязки
+const REG_ERROR = 2   //сервер отказал или соединение потеряно, скоро повтор
+const REG_CANCELLED = 3
+
+const pinLifetime = 5 * time.Minute
+const registrationRetryDelay = 10 * time.Second
+
+// адрес, который открывает приложение после сканирования QR-кода
+const pairingURL = "https://aircube.tech/pair"
+
+type Registration struct {
+	state      int
+	pin        string
+	nonce      string //случайное значение устройства, передается вместе с PIN в QR-коде и серверу
+	expires    time.Time
+	err        string
+	generation int //увеличивается при каждом перезапуске, старые обработчики ws и таймеры игнорируются
+}
+
+// RandomPIN - 4 цифры из криптографического генератора
+func RandomPIN() string {
+	n, err := rand.Int(rand.Reader, big.NewInt(10000))
+	if err != nil {
+		println("Random generator failed ", err.Error())
+		n = big.NewInt(time.Now().UnixNano() % 10000)
+	}
+	return fmt.Sprintf("%04d", n.Int64())
+}
+
+// RandomNonce - 8 случайных байт в hex
+func RandomNonce() string {
+	nonce := make([]byte, 8)
+	if _, err := rand.Read(nonce); err != nil {
+		println("Random generator failed ", err.Error())
+	}
+	return hex.EncodeToString(nonce)
+}
+
+// PairingURL - ссылка для привязки через приложение
+func (c *Cube) PairingURL() string {
+	params := url.Values{}
+	params.Set("pin", c.reg.pin)
+	params.Set("nonce", c.reg.nonce)
+	return pairingURL + "?" + params.Encode()
+}
+
+// Register показывает PIN и ждет, пока сервер не пришлет токен
+func (c *Cube) Register() {
+	c.reg.generation++
+	gen := c.reg.generation
+	c.reg.state = REG_WAITING
+	c.reg.err = ""
+	c.StartLightEffect(LightEffect{
+		Effect: LIGHT_BREATHING,
+		Colors: []string{"#C0C0C0"},
+		Period: 2560,
+	})
+	c.NewPIN()
+	c.ws.Call("addEventListener", "open", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		Update(func() {
+			if gen == c.reg.generation {
+				c.SendPIN()
+			}
+		})
+		return nil
+	}))
+	c.ws.Call("addEventListener", "message", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		data := args[0].Get("data").String()
+		Update(func() {
+			if gen == c.reg.generation && c.registration_mode {
+				c.OnRegistrationMessage(data)
+			}
+		})
+		return nil
+	}))
+	c.ws.Call("addEventListener", "close", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		Update(func() {
+			if gen == c.reg.generation && c.reg.state == REG_WAITING {
+				c.RegistrationFailed("connection lost")
+			}
+		})
+		return nil
+	}))
+}
+
+// NewPIN выбирает новый PIN; по истечении срока он заменяется автоматически
+func (c *Cube) NewPIN() {
+	pin := RandomPIN()
+	c.reg.pin = pin
+	c.reg.nonce = RandomNonce()
+	c.reg.expires = time.Now().Add(pinLifetime)
+	c.DrawPairing()
+	gen := c.reg.generation
+	time.AfterFunc(pinLifetime, func() {
+		Update(func() {
+			if gen == c.reg.generation && c.reg.state == REG_WAITING && c.reg.pin == pin {
+				println("PIN expired")
+				c.NewPIN()
+				c.SendPIN()
+			}
+		})
+	})
+	vecty.Rerender(emulator)
+}
+
+// DrawPairing выводит PIN цифрами или QR-кодом, в зависимости от выбранного режима
+func (c *Cube) DrawPairing() {
+	if c.pairingQR {
+		c.DrawPairingQR(c.PairingURL())
+		return
+	}
+	var digits int
+	fmt.Sscanf(c.reg.pin, "%d", &digits)
+	c.DrawPIN(digits)
+}
+
+// DrawPairingQR выводит QR-код на все грани, чтобы его можно было сканировать с любой стороны
+func (c *Cube) DrawPairingQR(text string) {
+	qr, err := EncodeQR([]byte(text), QR_M)
+	if err != nil {
+		println("QR code failed ", err.Error())
+		return
+	}
+	const quiet = 4 //светлая рамка вокруг кода в модулях
+	modules := qr.Size + quiet*2
+	scale := screenWidth / modules
+	if screenHeight/modules < scale {
+		scale = screenHeight / modules
+	}
+	if scale < 1 {
+		scale = 1
+	}
+	left := (screenWidth - modules*scale) / 2
+	top := (screenHeight - modules*scale) / 2
+	for s := range c.screens {
+		c.ClearScreen(s)
+		for y := 0; y < modules*scale; y++ {
+			for x := 0; x < modules*scale; x++ {
+				mx, my := x/scale-quiet, y/scale-quiet
+				if mx >= 0 && my >= 0 && mx < qr.Size && my < qr.Size && qr.Dark(mx, my) {
+					c.SetPixel(s, left+x, top+y, 0, 0, 0)
+				} else {
+					c.SetPixel(s, left+x, top+y, 255, 255, 255)
+				}
+			}
+		}
+	}
+}
+
+// TogglePairingQR переключает показ PIN цифрами и QR-кодом
+func (c *Cube) TogglePairingQR() {
+	c.pairingQR = !c.pairingQR
+	mode := []byte("pin")
+	if c.pairingQR {
+		mode = []byte("qr")
+	}
+	storage.Set(c.Key("pairing"), &mode)
+	if c.reg.state == REG_WAITING {
+		c.DrawPairing()
+	}
+	vecty.Rerender(emulator)
+}
+
+func (c *Cube) SendPIN() {
+	if c.ws.Get("readyState").Int() != 1 {
+		//PIN будет отправлен при открытии соединения
+		return
+	}
+	hello := HelloMessage{
+		Token: nil,
+		SN:    nil,
+		Pin:   &c.reg.pin,
+		Nonce: &c.reg.nonce,
+	}
+	hello_json, _ := json.Marshal(hello)
+	c.SendToServer(string(hello_json))
+}
+
+func (c *Cube) OnRegistrationMessage(data string) {
+	var db DeviceBound
+	if err := json.Unmarshal([]byte(data), &db); err != nil {
+		println("Unexpected registration message ", data)
+		return
+	}
+	if db.Error != nil {
+		c.RegistrationFailed(*db.Error)
+		return
+	}
+	if db.Token == "" {
+		return
+	}
+	c.reg.generation++
+	c.reg.state = REG_IDLE
+	c.token = &db.Token
+	c.sn = &db.SN
+	c.registration_mode = false
+	c.StopLightEffect()
+	c.powerOn = true
+	println("Token is ", *c.token)
+	c.StoreConfig()
+	c.ClearScreens()
+	c.LoggedIn(true)
+	vecty.Rerender(emulator)
+}
+
+// RegistrationFailed показывает ошибку на экранах и через некоторое время начинает привязку заново
+func (c *Cube) RegistrationFailed(message string) {
+	println("Registration failed ", message)
+	c.reg.state = REG_ERROR
+	c.reg.err = message
+	c.StartLightEffect(LightEffect{
+		Effect: LIGHT_BLINK,
+		Colors: []string{"#FF0000"},
+		Period: 1000,
+	})
+	c.ShowMessage([]string{"Pairing failed", "", message, "", "Retry in " + registrationRetryDelay.String()}, 255, 80, 80)
+	gen := c.reg.generation
+	time.AfterFunc(registrationRetryDelay, func() {
+		Update(func() {
+			if gen == c.reg.generation && c.reg.state == REG_ERROR && c.powerOn {
+				c.RestartRegistration()
+			}
+		})
+	})
+	vecty.Rerender(emulator)
+}
+
+// RestartRegistration открывает новое соединение и показывает новый PIN
+func (c *Cube) RestartRegistration() {
+	if !c.powerOn || !c.registration_mode {
+		return
+	}
+	c.reg.generation++
+	if c.socketConnected {
+		c.ws.Call("close")
+	}
+	c.ws = js.Global().Get("WebSocket").New(c.WSURL())
+	c.socketConnected = true
+	c.Register()
+}
+
+// CancelRegistration прекращает привязку; куб остается включенным до повторной попытки
+func (c *Cube) CancelRegistration() {
+	c.StopRegistration()
+	c.reg.state = REG_CANCELLED
+	if c.socketConnected {
+		c.ws.Call("close")
+		c.socketConnected = false
+	}
+	c.StopLightEffect()
+	c.ShowMessage([]string{"Pairing cancelled"}, 255, 255, 255)
+	vecty.Rerender(emulator)
+}
+
+func (c *Cube) StopRegistration() {
+	c.reg.generation++
+	c.reg.state = REG_IDLE
+}
+
+// ShowMessage выводит текст на все экраны
+func (c *Cube) ShowMessage(lines []string, r byte, g byte, b byte) {
+	perLine := screenWidth/8 - 2
+	var wrapped []string
+	for _, line := range lines {
+		for len([]rune(line)) > perLine {
+			cut := perLine
+			if space := strings.LastIndex(string([]rune(line)[:perLine]), " "); space > 0 {
+				cut = len([]rune(line[:space]))
+			}
+			wrapped = append(wrapped, string([]rune(line)[:cut]))
+			line = strings.TrimLeft(string([]rune(line)[cut:]), " ")
+		}
+		wrapped = append(wrapped, line)
+	}
+	y := (screenHeight - len(wrapped)*10) / 2
+	for s := range c.screens {
+		c.ClearScreen(s)
+		if font == nil {
+			continue
+		}
+		for i, line := range wrapped {
+			text := EncodeWindows1251([]byte(line))
+			c.PrintTextLine(text, 0, s, (screenWidth-len(text)*8)/2, y+i*10, r, g, b, nil)
+		}
+	}
+}
+
+type RegistrationPanel struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *RegistrationPanel) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	if !c.powerOn || !c.registration_mode {
+		return elem.Span()
+	}
+	var status string
+	switch c.reg.state {
+	case REG_WAITING:
+		status = "PIN " + c.reg.pin + " until " + c.reg.expires.Format("15:04:05")
+	case REG_ERROR:
+		status = "error: " + c.reg.err
+	case REG_CANCELLED:
+		status = "pairing cancelled"
+	}
+	button := func(ch string, title string, action func()) *vecty.HTML {
+		return elem.Span(vecty.Markup(
+			vecty.Class("registration-button"),
+			vecty.Property("title", title),
+			On("click", func(event *vecty.Event) {
+				action()
+			}),
+		), vecty.Text(ch))
+	}
+	return elem.Div(vecty.Markup(vecty.Class("centered", "registration")),
+		elem.Span(vecty.Markup(vecty.Class("registration-status")), vecty.Text(status)),
+		button("\uF021", "new PIN", c.RestartRegistration),
+		elem.Span(vecty.Markup(
+			vecty.Class("registration-button"),
+			vecty.MarkupIf(c.pairingQR, vecty.Class("active")),
+			vecty.Property("title", "show QR code"),
+			On("click", func(event *vecty.Event) {
+				c.TogglePairingQR()
+			}),
+		), vecty.Text("\uF029")),
+		vecty.If(c.reg.state != REG_CANCELLED, button("\uF05E", "cancel pairing", c.CancelRegistration)),
+	)
+}
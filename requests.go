@@ -0,0 +1,44 @@
+package main
+
+import "context"
+
+// ScreenRequest - последний запрос содержимого экрана
+type ScreenRequest struct {
+	seq    int
+	cancel context.CancelFunc
+}
+
+// ScreenRequests - запросы по экранам; более новый запрос экрана отменяет предыдущий.
+// Методы вызываются под блокировкой состояния.
+type ScreenRequests []ScreenRequest
+
+func (r ScreenRequests) Begin(screen int) (context.Context, int) {
+	r.Cancel(screen)
+	ctx, cancel := context.WithCancel(context.Background())
+	r[screen].cancel = cancel
+	return ctx, r[screen].seq
+}
+
+func (r ScreenRequests) Cancel(screen int) {
+	if r[screen].cancel != nil {
+		r[screen].cancel()
+		r[screen].cancel = nil
+	}
+	r[screen].seq++
+}
+
+func (r ScreenRequests) CancelAll() {
+	for i := range r {
+		r.Cancel(i)
+	}
+}
+
+// Finish завершает запрос seq; false, если его уже отменили, завершили или заменили более новым
+func (r ScreenRequests) Finish(screen int, seq int) bool {
+	if r[screen].seq != seq || r[screen].cancel == nil {
+		return false
+	}
+	r[screen].cancel()
+	r[screen].cancel = nil
+	return true
+}
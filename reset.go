@@ -0,0 +1,97 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"syscall/js"
+)
+
+const TYPE_UNBIND = 9 //пользователь отвязал куб
+
+// FactoryReset удаляет токен и возвращает куб в режим привязки.
+// При notify сервер перед этим получает сообщение об отвязке.
+func (c *Cube) FactoryReset(notify bool) {
+	println("Factory reset")
+	if notify && !c.registration_mode {
+		data, _ := json.Marshal(CubeInfo{Type: TYPE_UNBIND})
+		c.SendToServer(string(data))
+	}
+	c.ForgetToken()
+}
+
+// TokenRevoked вызывается, когда сервер отвечает 401: токен отозван, нужна новая привязка
+func (c *Cube) TokenRevoked() {
+	if c.registration_mode {
+		return
+	}
+	println("Token revoked by server")
+	c.ForgetToken()
+}
+
+// ForgetToken стирает токен (питание и адрес сервера сохраняются) и начинает привязку заново.
+// Куб отключается от выбранной учетной записи, чтобы не стереть в ней токен и серийный номер.
+func (c *Cube) ForgetToken() {
+	c.setIdentity("")
+	c.token = nil
+	c.sn = nil
+	c.registration_mode = true
+	c.StoreConfig()
+	c.Disconnect()
+	if c.powerOn {
+		c.PoweringOn()
+	}
+	vecty.Rerender(emulator)
+}
+
+// Disconnect закрывает соединение и убирает все, что было получено от сервера
+func (c *Cube) Disconnect() {
+	c.StopRegistration()
+	//эффект привязки иначе так и останется поверх цвета нового соединения
+	c.StopLightEffect()
+	c.CancelRequests()
+	c.ClearErrors()
+	c.ClearCache()
+	if c.socketConnected {
+		//сначала сбрасываем флаг, чтобы обработчик close не переподключался со старым токеном
+		c.socketConnected = false
+		c.ws.Call("close")
+	}
+	c.StopAnimations()
+	if c.soundPlaying {
+		c.StopSound()
+	}
+	for i := range c.screens {
+		c.screenLists[i] = nil
+		c.descriptors[i].list = false
+		c.descriptors[i].title = nil
+	}
+	c.ClearScreens()
+}
+
+type ResetPanel struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *ResetPanel) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	button := func(ch string, title string, question string, notify bool) *vecty.HTML {
+		return elem.Span(vecty.Markup(
+			vecty.Class("reset-button"),
+			vecty.Property("title", title),
+			On("click", func(event *vecty.Event) {
+				if js.Global().Call("confirm", question).Bool() {
+					c.FactoryReset(notify)
+				}
+			}),
+		), vecty.Text(ch))
+	}
+	return elem.Span(vecty.Markup(vecty.Class("reset")),
+		vecty.If(!c.registration_mode, button("\uF127", "unbind device", "Unbind the cube from your account?", true)),
+		button("\uF0E2", "factory reset", "Reset the cube to factory settings?", false),
+	)
+}
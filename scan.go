@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const SCAN_ROWS = "rows"       //по строкам сверху вниз, каждая слева направо
+const SCAN_COLUMNS = "columns" //по столбцам слева направо, каждый сверху вниз
+
+// ScanMode - порядок пикселей в изображении.
+// Изображение читается в порядке Order, при Mirror отражается слева направо,
+// затем поворачивается по часовой стрелке на Rotation градусов и выводится на экран.
+// При повороте на 90 и 270 исходное изображение имеет размер height x width.
+//
+// Первая ревизия куба передает изображение по столбцам снизу вверх, это
+// {columns, 180, mirror}
+type ScanMode struct {
+	Order    string `json:"scan_order"`
+	Rotation int    `json:"rotation"`
+	Mirror   bool   `json:"mirror"`
+}
+
+// ParseScanMode разбирает запись вида "columns/180/mirror". Запись с порядком пикселей описывает режим
+// полностью: без угла поворот 0, без mirror отражения нет. Запись без порядка ("90", "mirror")
+// меняет только указанные части base.
+func ParseScanMode(s string, base ScanMode) ScanMode {
+	var parts []string
+	mode := base
+	for _, part := range strings.Split(s, "/") {
+		part = strings.TrimSpace(strings.ToLower(part))
+		if part == SCAN_ROWS || part == SCAN_COLUMNS {
+			mode = ScanMode{}
+		}
+		parts = append(parts, part)
+	}
+	for _, part := range parts {
+		switch part {
+		case SCAN_ROWS, SCAN_COLUMNS:
+			mode.Order = part
+		case "mirror", "mirrored":
+			mode.Mirror = true
+		case "0", "90", "180", "270":
+			mode.Rotation, _ = strconv.Atoi(part)
+		}
+	}
+	return mode
+}
+
+func (m ScanMode) String() string {
+	s := m.Order + "/" + strconv.Itoa(m.Rotation)
+	if m.Mirror {
+		s += "/mirror"
+	}
+	return s
+}
+
+// WithHeaders позволяет серверу указать порядок пикселей для конкретного изображения
+// заголовками X-Scan-Order, X-Rotation и X-Mirror (сервер должен открыть их через Access-Control-Expose-Headers).
+// Как и в ParseScanMode, заголовок с порядком описывает режим полностью.
+func (m ScanMode) WithHeaders(h http.Header) ScanMode {
+	if order := strings.TrimSpace(strings.ToLower(h.Get("X-Scan-Order"))); order == SCAN_ROWS || order == SCAN_COLUMNS {
+		m = ScanMode{Order: order}
+	}
+	if rotation, err := strconv.Atoi(h.Get("X-Rotation")); err == nil {
+		m.Rotation = rotation
+	}
+	if mirror, err := strconv.ParseBool(h.Get("X-Mirror")); err == nil {
+		m.Mirror = mirror
+	}
+	return m
+}
+
+// Position - координаты на экране width x height i-го пикселя изображения
+func (m ScanMode) Position(i int, width int, height int) (x, y int) {
+	rotation := ((m.Rotation%360 + 360) % 360) / 90 * 90
+	srcW, srcH := width, height
+	if rotation == 90 || rotation == 270 {
+		srcW, srcH = height, width
+	}
+	var u, v int
+	if m.Order == SCAN_COLUMNS {
+		u, v = i/srcH, i%srcH
+	} else {
+		u, v = i%srcW, i/srcW
+	}
+	if m.Mirror {
+		u = srcW - 1 - u
+	}
+	switch rotation {
+	case 90:
+		return srcH - 1 - v, u
+	case 180:
+		return srcW - 1 - u, srcH - 1 - v
+	case 270:
+		return v, srcW - 1 - u
+	}
+	return u, v
+}
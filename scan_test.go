@@ -0,0 +1,187 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+)
+
+// Эталонное изображение 3x2: в каждой ячейке - номер пикселя в потоке, который должен оказаться
+// в этой точке экрана. Таблица построена поворотом и отражением сетки, независимо от Position.
+var scanReference = []struct {
+	mode   ScanMode
+	screen [][]int
+}{
+	{ScanMode{SCAN_ROWS, 0, false}, [][]int{{0, 1, 2}, {3, 4, 5}}},
+	{ScanMode{SCAN_ROWS, 0, true}, [][]int{{2, 1, 0}, {5, 4, 3}}},
+	{ScanMode{SCAN_ROWS, 90, false}, [][]int{{4, 2, 0}, {5, 3, 1}}},
+	{ScanMode{SCAN_ROWS, 90, true}, [][]int{{5, 3, 1}, {4, 2, 0}}},
+	{ScanMode{SCAN_ROWS, 180, false}, [][]int{{5, 4, 3}, {2, 1, 0}}},
+	{ScanMode{SCAN_ROWS, 180, true}, [][]int{{3, 4, 5}, {0, 1, 2}}},
+	{ScanMode{SCAN_ROWS, 270, false}, [][]int{{1, 3, 5}, {0, 2, 4}}},
+	{ScanMode{SCAN_ROWS, 270, true}, [][]int{{0, 2, 4}, {1, 3, 5}}},
+	{ScanMode{SCAN_COLUMNS, 0, false}, [][]int{{0, 2, 4}, {1, 3, 5}}},
+	{ScanMode{SCAN_COLUMNS, 0, true}, [][]int{{4, 2, 0}, {5, 3, 1}}},
+	{ScanMode{SCAN_COLUMNS, 90, false}, [][]int{{2, 1, 0}, {5, 4, 3}}},
+	{ScanMode{SCAN_COLUMNS, 90, true}, [][]int{{5, 4, 3}, {2, 1, 0}}},
+	{ScanMode{SCAN_COLUMNS, 180, false}, [][]int{{5, 3, 1}, {4, 2, 0}}},
+	{ScanMode{SCAN_COLUMNS, 180, true}, [][]int{{1, 3, 5}, {0, 2, 4}}},
+	{ScanMode{SCAN_COLUMNS, 270, false}, [][]int{{3, 4, 5}, {0, 1, 2}}},
+	{ScanMode{SCAN_COLUMNS, 270, true}, [][]int{{0, 1, 2}, {3, 4, 5}}},
+}
+
+func TestScanModePosition(t *testing.T) {
+	const width, height = 3, 2
+	for _, test := range scanReference {
+		screen := [height][width]int{}
+		for y := range screen {
+			for x := range screen[y] {
+				screen[y][x] = -1
+			}
+		}
+		for i := 0; i < width*height; i++ {
+			x, y := test.mode.Position(i, width, height)
+			if x < 0 || y < 0 || x >= width || y >= height {
+				t.Errorf("%s: pixel %d is out of the screen at %d,%d", test.mode, i, x, y)
+				continue
+			}
+			if screen[y][x] != -1 {
+				t.Errorf("%s: pixels %d and %d are both at %d,%d", test.mode, screen[y][x], i, x, y)
+			}
+			screen[y][x] = i
+		}
+		for y := range screen {
+			for x := range screen[y] {
+				if screen[y][x] != test.screen[y][x] {
+					t.Errorf("%s: got %v, want %v", test.mode, screen, test.screen)
+					return
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeScreenImage(t *testing.T) {
+	saved := profile
+	defer SetProfile(saved)
+	SetProfile(DeviceProfile{Name: "test", Width: 3, Height: 2, Faces: 1, PixelFormat: PIXEL_RGB888})
+	img := make([]byte, 0, 6*3)
+	for i := 0; i < 6; i++ {
+		img = append(img, byte(10+i), byte(20+i), byte(30+i))
+	}
+	for _, test := range scanReference {
+		frame := DecodeScreenImage(img, test.mode)
+		for y := range test.screen {
+			for x, i := range test.screen[y] {
+				pos := (y*3 + x) * 4
+				if frame[pos] != byte(10+i) || frame[pos+1] != byte(20+i) || frame[pos+2] != byte(30+i) || frame[pos+3] != 255 {
+					t.Errorf("%s: pixel %d,%d is %v, want pixel %d", test.mode, x, y, frame[pos:pos+4], i)
+				}
+			}
+		}
+	}
+}
+
+// Первая ревизия куба: изображение идет по столбцам слева направо, каждый снизу вверх.
+// Так его выводил SetScreen до появления ScanMode.
+func TestScanModeBaseline(t *testing.T) {
+	const width, height = 160, 128
+	mode := ScanMode{Order: SCAN_COLUMNS, Rotation: 180, Mirror: true}
+	for i := 0; i < width*height; i++ {
+		x, y := mode.Position(i, width, height)
+		if x != i/height || y != height-1-i%height {
+			t.Fatalf("pixel %d is at %d,%d, want %d,%d", i, x, y, i/height, height-1-i%height)
+		}
+	}
+	if profiles["aircube"].Scan != mode {
+		t.Errorf("aircube profile scans %s, want %s", profiles["aircube"].Scan, mode)
+	}
+}
+
+func TestScanModeRotationIsNormalized(t *testing.T) {
+	for _, test := range []struct{ rotation, same int }{{-90, 270}, {360, 0}, {450, 90}, {-180, 180}, {100, 90}} {
+		for i := 0; i < 6; i++ {
+			x1, y1 := ScanMode{SCAN_ROWS, test.rotation, false}.Position(i, 3, 2)
+			x2, y2 := ScanMode{SCAN_ROWS, test.same, false}.Position(i, 3, 2)
+			if x1 != x2 || y1 != y2 {
+				t.Errorf("rotation %d differs from %d at pixel %d", test.rotation, test.same, i)
+			}
+		}
+	}
+}
+
+func TestParseScanMode(t *testing.T) {
+	bases := []ScanMode{{SCAN_ROWS, 0, false}, {SCAN_COLUMNS, 180, true}}
+	for _, test := range scanReference {
+		for _, base := range bases {
+			if mode := ParseScanMode(test.mode.String(), base); mode != test.mode {
+				t.Errorf("%q with base %s is parsed to %s", test.mode.String(), base, mode)
+			}
+		}
+	}
+	base := ScanMode{SCAN_COLUMNS, 180, true}
+	for _, test := range []struct {
+		s    string
+		mode ScanMode
+	}{
+		{"", base},
+		{"90", ScanMode{SCAN_COLUMNS, 90, true}},
+		{"0", ScanMode{SCAN_COLUMNS, 0, true}},
+		{"diagonal/45", base},
+		{"rows", ScanMode{SCAN_ROWS, 0, false}},
+		{"columns/270", ScanMode{SCAN_COLUMNS, 270, false}},
+		{" Rows / 270 / Mirrored ", ScanMode{SCAN_ROWS, 270, true}},
+		{"mirror/90/columns", ScanMode{SCAN_COLUMNS, 90, true}},
+	} {
+		if mode := ParseScanMode(test.s, base); mode != test.mode {
+			t.Errorf("%q is parsed to %s, want %s", test.s, mode, test.mode)
+		}
+	}
+	if mode := ParseScanMode("mirror", ScanMode{SCAN_ROWS, 90, false}); mode != (ScanMode{SCAN_ROWS, 90, true}) {
+		t.Errorf("mirror is parsed to %s", mode)
+	}
+}
+
+func TestScanModeWithHeaders(t *testing.T) {
+	bases := []ScanMode{{SCAN_ROWS, 0, false}, {SCAN_COLUMNS, 180, true}}
+	for _, test := range scanReference {
+		for _, base := range bases {
+			h := http.Header{}
+			h.Set("X-Scan-Order", test.mode.Order)
+			h.Set("X-Rotation", strconv.Itoa(test.mode.Rotation))
+			h.Set("X-Mirror", strconv.FormatBool(test.mode.Mirror))
+			if mode := base.WithHeaders(h); mode != test.mode {
+				t.Errorf("headers %v with base %s give %s", h, base, mode)
+			}
+		}
+	}
+	base := ScanMode{SCAN_COLUMNS, 180, true}
+	if mode := base.WithHeaders(http.Header{}); mode != base {
+		t.Errorf("no headers change %s to %s", base, mode)
+	}
+	//scan - та же запись в поле scan анимации, режим должен совпасть
+	for _, test := range []struct {
+		order, rotation, mirror, scan string
+		mode                          ScanMode
+	}{
+		{"ROWS", "", "", "rows", ScanMode{SCAN_ROWS, 0, false}},
+		{"ROWS", "bad", "bad", "rows", ScanMode{SCAN_ROWS, 0, false}},
+		{"columns", "90", "", "columns/90", ScanMode{SCAN_COLUMNS, 90, false}},
+		{"", "90", "", "90", ScanMode{SCAN_COLUMNS, 90, true}},
+		{"", "", "false", "", ScanMode{SCAN_COLUMNS, 180, false}},
+		{"diagonal", "", "", "diagonal", base},
+	} {
+		h := http.Header{}
+		for key, value := range map[string]string{"X-Scan-Order": test.order, "X-Rotation": test.rotation, "X-Mirror": test.mirror} {
+			if value != "" {
+				h.Set(key, value)
+			}
+		}
+		if mode := base.WithHeaders(h); mode != test.mode {
+			t.Errorf("headers %v give %s, want %s", h, mode, test.mode)
+		}
+		if test.scan != "" && ParseScanMode(test.scan, base) != test.mode {
+			t.Errorf("%q is parsed to %s, headers give %s", test.scan, ParseScanMode(test.scan, base), test.mode)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+// ScreenContent - изображение экрана (RGBA) и переходы между изображениями
+type ScreenContent struct {
+	points     []byte
+	frame      []byte
+	transition *Transition
+	pending    *Transition
+}
+
+// ScreenState - экраны куба и запросы их содержимого у сервера. Не зависят от браузера,
+// поэтому работу с ними под блокировкой состояния можно проверить в go test -race.
+type ScreenState struct {
+	screens   []ScreenContent
+	rotations []int
+	requests  ScreenRequests
+}
+
+func NewScreenState(count int) ScreenState {
+	s := ScreenState{
+		screens:   make([]ScreenContent, count),
+		rotations: make([]int, count),
+		requests:  make(ScreenRequests, count),
+	}
+	for i := range s.screens {
+		s.screens[i].points = make([]byte, screenWidth*screenHeight*4)
+	}
+	return s
+}
+
+func (s *ScreenState) ClearScreen(screen int) {
+	j := 0
+	pixels := s.screens[screen].points
+	for j < screenWidth*screenHeight {
+		pixels[j*4] = 0
+		pixels[j*4+1] = 0
+		pixels[j*4+2] = 0
+		pixels[j*4+3] = 255
+		j++
+	}
+}
+
+func (s *ScreenState) ClearScreens() {
+	for i := range s.screens {
+		s.ClearScreen(i)
+	}
+}
+
+func (s *ScreenState) SetPoint(screen int, img []byte, i int, pos int) {
+	r, g, b, ok := profile.DecodePixel(img, i)
+	if !ok {
+		return
+	}
+	if s.rotations[screen] != 180 {
+		pos = screenHeight*screenWidth - 1 - pos
+	}
+	s.screens[screen].points[pos*4] = r
+	s.screens[screen].points[pos*4+1] = g
+	s.screens[screen].points[pos*4+2] = b
+	s.screens[screen].points[pos*4+3] = 255
+}
@@ -0,0 +1,143 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"bytes"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"github.com/hexops/vecty/prop"
+	"image"
+	"image/png"
+	"strconv"
+	"syscall/js"
+	"time"
+)
+
+const compositeGap = 8 //px между гранями на общем снимке
+
+// Frame - изображение грани в том виде, в каком оно сейчас на экране
+func (c *Cube) Frame(screen int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, screenWidth, screenHeight))
+	copy(img.Pix, c.ScreenFrame(screen))
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	return img
+}
+
+// CompositeFrame - все грани в ряд
+func (c *Cube) CompositeFrame() *image.RGBA {
+	count := len(c.screens)
+	img := image.NewRGBA(image.Rect(0, 0, count*screenWidth+(count-1)*compositeGap, screenHeight))
+	for i := 3; i < len(img.Pix); i += 4 {
+		img.Pix[i] = 255
+	}
+	for s := 0; s < count; s++ {
+		face := c.Frame(s)
+		x0 := s * (screenWidth + compositeGap)
+		for y := 0; y < screenHeight; y++ {
+			copy(img.Pix[img.PixOffset(x0, y):], face.Pix[face.PixOffset(0, y):face.PixOffset(screenWidth, y)])
+		}
+	}
+	return img
+}
+
+// Upscale увеличивает изображение в целое число раз без сглаживания
+func Upscale(src *image.RGBA, factor int) *image.RGBA {
+	if factor <= 1 {
+		return src
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx()*factor, b.Dy()*factor))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			s := src.PixOffset(b.Min.X+x/factor, b.Min.Y+y/factor)
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[s:s+4])
+		}
+	}
+	return dst
+}
+
+const downloadRevokeDelay = 40 * time.Second
+
+// Download отдает данные браузеру как файл
+func Download(data []byte, mime string, filename string) {
+	array := js.Global().Get("Uint8Array").New(len(data))
+	js.CopyBytesToJS(array, data)
+	blob := js.Global().Get("Blob").New([]interface{}{array}, map[string]interface{}{"type": mime})
+	url := js.Global().Get("URL").Call("createObjectURL", blob)
+	a := js.Global().Get("document").Call("createElement", "a")
+	a.Set("href", url)
+	a.Set("download", filename)
+	a.Call("click")
+	//браузер начинает загрузку не сразу, ссылку освобождаем позже
+	revoke := js.Global().Get("URL").Get("revokeObjectURL").Call("bind", js.Global().Get("URL"), url)
+	js.Global().Call("setTimeout", revoke, downloadRevokeDelay.Milliseconds())
+}
+
+func SavePNG(img image.Image, filename string) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		println("PNG can't be encoded ", err.Error())
+		return
+	}
+	Download(buf.Bytes(), "image/png", filename)
+}
+
+func (c *Cube) SnapshotName(what string) string {
+	return "cube" + strconv.Itoa(c.id) + "-" + what + "-" + time.Now().Format("20060102-150405") + ".png"
+}
+
+func (c *Cube) SaveFace(screen int) {
+	SavePNG(Upscale(c.Frame(screen), c.snapshotScale), c.SnapshotName("face"+strconv.Itoa(screen+1)))
+}
+
+func (c *Cube) SaveAllFaces() {
+	SavePNG(Upscale(c.CompositeFrame(), c.snapshotScale), c.SnapshotName("faces"))
+}
+
+var snapshotScales = []int{1, 2, 3, 4}
+
+type SnapshotPanel struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *SnapshotPanel) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	var faces vecty.List
+	for i := range c.screens {
+		screen := i
+		faces = append(faces, elem.Span(vecty.Markup(
+			vecty.Class("snapshot-face"),
+			vecty.Property("title", "save face "+strconv.Itoa(screen+1)),
+			On("click", func(event *vecty.Event) {
+				c.SaveFace(screen)
+			}),
+		), vecty.Text(strconv.Itoa(screen+1))))
+	}
+	var scales vecty.List
+	for _, s := range snapshotScales {
+		scales = append(scales, elem.Option(vecty.Markup(
+			prop.Value(strconv.Itoa(s)),
+			vecty.Property("selected", s == c.snapshotScale),
+		), vecty.Text(strconv.Itoa(s)+"x")))
+	}
+	return elem.Span(vecty.Markup(vecty.Class("snapshot")),
+		elem.Span(vecty.Markup(
+			vecty.Class("snapshot-button"),
+			vecty.Property("title", "save all faces"),
+			On("click", func(event *vecty.Event) {
+				c.SaveAllFaces()
+			}),
+		), vecty.Text("\uF030")),
+		faces,
+		elem.Select(vecty.Markup(
+			On("change", func(e *vecty.Event) {
+				c.snapshotScale, _ = strconv.Atoi(e.Target.Get("value").String())
+			}),
+		), scales),
+	)
+}
@@ -0,0 +1,285 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"github.com/hexops/vecty/prop"
+	"math"
+	"strconv"
+	"syscall/js"
+	"time"
+)
+
+const SHAKE_LIGHT = 1
+const SHAKE_MEDIUM = 2
+const SHAKE_STRONG = 3
+
+// параметры детектора встряхивания (как в прошивке)
+const shakeThreshold = 12.0 //м/с^2, без учета силы тяжести
+const shakeReversals = 4    //число смен направления
+const shakeWindow = 800 * time.Millisecond
+const shakeCooldown = 1500 * time.Millisecond
+
+const shakeSampleInterval = 20 * time.Millisecond
+const shakeFrequency = 5.0 //Гц
+
+const shakeMinDuration = 200 //ms
+const shakeMaxDuration = 5000
+
+// ShakeModel - параметры имитации встряхивания
+type ShakeModel struct {
+	Intensity int `json:"intensity"`
+	Duration  int `json:"duration"` //ms
+}
+
+func DefaultShakeModel() ShakeModel {
+	return ShakeModel{Intensity: SHAKE_MEDIUM, Duration: 1500}
+}
+
+// Amplitude - амплитуда ускорения, которую детектор распознает как заданную силу встряхивания
+func (m ShakeModel) Amplitude() float64 {
+	return shakeThreshold * (float64(m.Intensity) + 0.5)
+}
+
+// ShakeDetector считает смены направления ускорения выше порога
+type ShakeDetector struct {
+	lastSign    int
+	reversals   int
+	windowStart time.Time
+	lastShake   time.Time
+	peak        float64
+}
+
+// Sample обрабатывает очередное измерение; возвращает силу встряхивания или 0
+// и признак смены направления выше порога (по нему прошивка передает ускорение)
+func (d *ShakeDetector) Sample(a [3]float64, t time.Time) (int, bool) {
+	axis := 0
+	for i := 1; i < 3; i++ {
+		if math.Abs(a[i]) > math.Abs(a[axis]) {
+			axis = i
+		}
+	}
+	value := math.Abs(a[axis])
+	if value < shakeThreshold {
+		return 0, false
+	}
+	sign := 1
+	if a[axis] < 0 {
+		sign = -1
+	}
+	if d.reversals == 0 || t.Sub(d.windowStart) > shakeWindow {
+		d.reversals = 0
+		d.peak = 0
+		d.windowStart = t
+	}
+	if value > d.peak {
+		d.peak = value
+	}
+	reversal := sign != d.lastSign
+	if reversal {
+		d.lastSign = sign
+		d.reversals++
+	}
+	if d.reversals < shakeReversals || t.Sub(d.lastShake) < shakeCooldown {
+		return 0, reversal
+	}
+	d.reversals = 0
+	d.lastShake = t
+	intensity := int(d.peak / shakeThreshold)
+	if intensity < SHAKE_LIGHT {
+		intensity = SHAKE_LIGHT
+	}
+	if intensity > SHAKE_STRONG {
+		intensity = SHAKE_STRONG
+	}
+	return intensity, reversal
+}
+
+func (c *Cube) LoadShakeModel() {
+	c.shake = DefaultShakeModel()
+	stored := storage.Get(c.Key("shake"))
+	if stored != nil {
+		json.Unmarshal([]byte(*stored), &c.shake)
+	}
+}
+
+func (c *Cube) SetShakeModel(m ShakeModel) {
+	c.shake = m
+	data, _ := json.Marshal(m)
+	storage.Set(c.Key("shake"), &data)
+	vecty.Rerender(emulator)
+}
+
+// ShakeSample передает измерение акселерометра детектору куба
+func (c *Cube) ShakeSample(a [3]float64) {
+	if !c.powerOn {
+		return
+	}
+	intensity, reversal := c.shakeDetector.Sample(a, time.Now())
+	if reversal {
+		//как в прошивке: ускорение передается на каждом рывке, встряхивание - после нескольких
+		up := c.orientation.Up()
+		data, _ := json.Marshal(CubeInfo{Type: TYPE_ACCEL, State: &up, Acceleration: &a})
+		c.SendToServer(string(data))
+	}
+	if intensity == 0 {
+		return
+	}
+	//State - сила встряхивания, старые серверы его не читают
+	data, _ := json.Marshal(CubeInfo{Type: TYPE_SHAKING, State: &intensity})
+	c.SendToServer(string(data))
+}
+
+// Shake имитирует встряхивание по модели куба
+func (c *Cube) Shake() {
+	c.SimulateShake(c.shake)
+}
+
+func (c *Cube) SimulateShake(m ShakeModel) {
+	duration := time.Duration(m.Duration) * time.Millisecond
+	c.ShakeEffect(m.Intensity, duration)
+	go func() {
+		amplitude := m.Amplitude()
+		start := time.Now()
+		for t := time.Duration(0); t < duration; t += shakeSampleInterval {
+			x := amplitude * math.Sin(2*math.Pi*shakeFrequency*t.Seconds())
+			Update(func() {
+				c.ShakeSample([3]float64{x, 0, 0})
+			})
+			time.Sleep(time.Until(start.Add(t + shakeSampleInterval)))
+		}
+	}()
+}
+
+// ShakeEffect - эффект "встряхивания"; в объемном виде трясется весь куб
+func (c *Cube) ShakeEffect(intensity int, duration time.Duration) {
+	var views []js.Value
+	if c.view3D {
+		views = append(views, c.Element(".scene"))
+	} else {
+		for i := range c.screens {
+			views = append(views, c.Element(".screen"+strconv.Itoa(i)))
+		}
+	}
+	class := "shake-" + strconv.Itoa(intensity)
+	for _, view := range views {
+		view.Get("classList").Call("add", "shaking", class)
+	}
+	go func() {
+		time.Sleep(duration)
+		Update(func() {
+			for _, view := range views {
+				view.Get("classList").Call("remove", "shaking", class)
+			}
+		})
+	}()
+}
+
+// InitDeviceMotion подключает акселерометр телефона к кубам с включенным датчиком
+func InitDeviceMotion() {
+	js.Global().Call("addEventListener", "devicemotion", js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		acc := args[0].Get("acceleration")
+		if !acc.Truthy() || acc.Get("x").IsNull() {
+			return nil
+		}
+		a := [3]float64{acc.Get("x").Float(), acc.Get("y").Float(), acc.Get("z").Float()}
+		Update(func() {
+			for _, c := range cubes {
+				if c.motion {
+					c.ShakeSample(a)
+				}
+			}
+		})
+		return nil
+	}))
+}
+
+// ToggleMotion включает встряхивание телефоном (iOS требует разрешения по нажатию)
+func (c *Cube) ToggleMotion() {
+	if c.motion {
+		c.motion = false
+		vecty.Rerender(emulator)
+		return
+	}
+	dme := js.Global().Get("DeviceMotionEvent")
+	if !dme.Truthy() {
+		println("Device motion isn't supported")
+		return
+	}
+	if !dme.Get("requestPermission").Truthy() {
+		c.motion = true
+		vecty.Rerender(emulator)
+		return
+	}
+	var then js.Func
+	then = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		then.Release()
+		Update(func() {
+			c.motion = args[0].String() == "granted"
+			vecty.Rerender(emulator)
+		})
+		return nil
+	})
+	dme.Call("requestPermission").Call("then", then)
+}
+
+type ShakeControl struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *ShakeControl) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	return elem.Span(vecty.Markup(vecty.Class("shake-control")),
+		elem.Span(vecty.Markup(
+			vecty.Class("shake-button"),
+			vecty.Property("title", "shake"),
+			On("click", func(event *vecty.Event) {
+				if c.powerOn {
+					c.Shake()
+				}
+			}),
+		), vecty.Text("\uF0E7")),
+		elem.Input(vecty.Markup(
+			prop.Type(prop.TypeRange),
+			vecty.Attribute("min", SHAKE_LIGHT),
+			vecty.Attribute("max", SHAKE_STRONG),
+			vecty.Property("title", "intensity"),
+			prop.Value(strconv.Itoa(c.shake.Intensity)),
+			On("change", func(e *vecty.Event) {
+				m := c.shake
+				m.Intensity, _ = strconv.Atoi(e.Target.Get("value").String())
+				c.SetShakeModel(m)
+			}),
+		)),
+		elem.Input(vecty.Markup(
+			prop.Type(prop.TypeNumber),
+			vecty.Attribute("min", shakeMinDuration),
+			vecty.Attribute("max", shakeMaxDuration),
+			vecty.Attribute("step", 100),
+			vecty.Property("title", "duration, ms"),
+			prop.Value(strconv.Itoa(c.shake.Duration)),
+			On("change", func(e *vecty.Event) {
+				duration, err := strconv.Atoi(e.Target.Get("value").String())
+				if err != nil || duration < shakeMinDuration || duration > shakeMaxDuration {
+					return
+				}
+				m := c.shake
+				m.Duration = duration
+				c.SetShakeModel(m)
+			}),
+		)),
+		elem.Span(vecty.Markup(
+			vecty.Class("motion-button"),
+			vecty.MarkupIf(c.motion, vecty.Class("on")),
+			vecty.Property("title", "device motion"),
+			On("click", func(event *vecty.Event) {
+				c.ToggleMotion()
+			}),
+		), vecty.Text("\uF10B")),
+	)
+}
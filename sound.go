@@ -0,0 +1,155 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"strconv"
+	"strings"
+	"syscall/js"
+	"time"
+)
+
+const buzzerVolume = 0.1
+
+type Buzzer interface {
+	Play(notes []Note)
+	Stop()
+}
+
+func (c *Cube) InitBuzzer() {
+	search := js.Global().Get("location").Get("search").String()
+	if strings.Contains(search, "headless") {
+		c.buzzer = &LogBuzzer{cube: c.id}
+	} else {
+		c.buzzer = &WebAudioBuzzer{}
+	}
+	m := storage.Get(c.Key("muted"))
+	c.muted = m != nil && *m == "true"
+}
+
+func (c *Cube) PlaySound(sound SoundInfo) {
+	if sound.Stop {
+		c.StopSound()
+		return
+	}
+	notes, err := sound.ToNotes()
+	if err != nil {
+		println("Sound can't be played ", err.Error())
+		return
+	}
+	if !c.muted {
+		c.buzzer.Play(notes)
+	}
+	total := 0
+	for _, n := range notes {
+		total += n.Duration
+	}
+	end := time.Now().Add(time.Duration(total) * time.Millisecond)
+	c.soundEnd = end
+	c.soundPlaying = true
+	vecty.Rerender(emulator)
+	go func() {
+		time.Sleep(time.Until(end))
+		Update(func() {
+			if c.soundEnd == end {
+				c.soundPlaying = false
+				vecty.Rerender(emulator)
+			}
+		})
+	}()
+}
+
+func (c *Cube) StopSound() {
+	c.buzzer.Stop()
+	c.soundEnd = time.Now()
+	c.soundPlaying = false
+	vecty.Rerender(emulator)
+}
+
+type WebAudioBuzzer struct {
+	context     js.Value
+	oscillators []js.Value
+}
+
+func (b *WebAudioBuzzer) Play(notes []Note) {
+	b.Stop()
+	if b.context.IsUndefined() {
+		b.context = js.Global().Get("AudioContext").New()
+	}
+	//контекст может быть приостановлен браузером до первого действия пользователя
+	b.context.Call("resume")
+	at := b.context.Get("currentTime").Float()
+	for _, n := range notes {
+		duration := float64(n.Duration) / 1000
+		if n.Frequency > 0 {
+			osc := b.context.Call("createOscillator")
+			osc.Set("type", "square")
+			osc.Get("frequency").Set("value", n.Frequency)
+			gain := b.context.Call("createGain")
+			gain.Get("gain").Set("value", buzzerVolume)
+			osc.Call("connect", gain)
+			gain.Call("connect", b.context.Get("destination"))
+			osc.Call("start", at)
+			osc.Call("stop", at+duration)
+			b.oscillators = append(b.oscillators, osc)
+		}
+		at += duration
+	}
+}
+
+func (b *WebAudioBuzzer) Stop() {
+	for _, osc := range b.oscillators {
+		osc.Call("stop")
+	}
+	b.oscillators = nil
+}
+
+// LogBuzzer не воспроизводит звук, а пишет ноты в консоль и в window.aircubeNotes (для тестов)
+type LogBuzzer struct {
+	cube int
+}
+
+func (b *LogBuzzer) Play(notes []Note) {
+	played := js.Global().Get("aircubeNotes")
+	if played.IsUndefined() {
+		played = js.Global().Get("Array").New()
+		js.Global().Set("aircubeNotes", played)
+	}
+	for _, n := range notes {
+		println("Cube ", b.cube, " note ", strconv.FormatFloat(n.Frequency, 'f', 1, 64), " ", n.Duration)
+		played.Call("push", map[string]interface{}{"cube": b.cube, "frequency": n.Frequency, "duration": n.Duration})
+	}
+}
+
+func (b *LogBuzzer) Stop() {
+	println("Cube ", b.cube, " sound stopped")
+}
+
+type SoundIndicator struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *SoundIndicator) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	ch := "\uF028"
+	if c.muted {
+		ch = "\uF026"
+	}
+	return elem.Span(vecty.Markup(
+		vecty.Class("sound-button"),
+		vecty.MarkupIf(c.soundPlaying, vecty.Class("playing")),
+		On("click", func(event *vecty.Event) {
+			c.muted = !c.muted
+			state := []byte(strconv.FormatBool(c.muted))
+			storage.Set(c.Key("muted"), &state)
+			if c.muted {
+				c.buzzer.Stop()
+			}
+			vecty.Rerender(emulator)
+		}),
+	), vecty.Text(ch))
+}
@@ -0,0 +1,19 @@
+package main
+
+import "sync"
+
+// stateMu охраняет состояние эмулятора: кубы, экраны, списки, дескрипторы, активный экран,
+// ориентацию, хранилище настроек. Каждое изменение или чтение состояния выполняется внутри Update:
+// обработчики событий DOM (через On из state_js.go), обратные вызовы JS, таймеры, горутины сети и анимации,
+// отрисовка canvas. Так отрисовка никогда не видит наполовину обновленный список или экран.
+//
+// Внутри Update нельзя ждать (сеть, каналы без буфера, Sleep) и нельзя снова вызывать Update:
+// в браузере все выполняется в одном потоке, и ожидание под блокировкой остановит обработку событий.
+// Функции, которые сами вызывают Update, - это только точки входа: тела горутин, таймеров и обработчиков.
+var stateMu sync.Mutex
+
+func Update(f func()) {
+	stateMu.Lock()
+	defer stateMu.Unlock()
+	f()
+}
@@ -0,0 +1,12 @@
+package main
+
+import "github.com/hexops/vecty"
+
+// On - обработчик события DOM, выполняемый под блокировкой состояния
+func On(name string, listener func(event *vecty.Event)) *vecty.EventListener {
+	return &vecty.EventListener{Name: name, Listener: func(event *vecty.Event) {
+		Update(func() {
+			listener(event)
+		})
+	}}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+)
+
+// Несколько горутин запрашивают экраны куба, как FetchScreen, и выводят ответ с переходом,
+// как GetImageFromNetwork; отрисовка берет кадры через ScreenFrame, как render loop canvas,
+// а Disconnect отменяет запросы. Все обращения к экранам идут через Update;
+// go test -race проверяет, что их действительно разделяет блокировка.
+func TestUpdateSerializesScreenRequests(t *testing.T) {
+	const fetchers = 8
+	const rounds = 50
+	s := NewScreenState(faceCount)
+	fade := 1
+	applied := 0
+
+	var wg sync.WaitGroup
+	for f := 0; f < fetchers; f++ {
+		wg.Add(1)
+		go func(f int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				screen := (f + r) % len(s.screens)
+				var seq int
+				Update(func() {
+					_, seq = s.requests.Begin(screen)
+				})
+				//ответ сервера приходит без блокировки: одноцветное изображение
+				img := make([]byte, screenWidth*screenHeight*profile.BytesPerPixel())
+				for i := range img {
+					img[i] = byte(f*rounds + r)
+				}
+				Update(func() {
+					if !s.requests.Finish(screen, seq) {
+						return
+					}
+					s.QueueTransition(screen, TRANSITION_FADE, &fade)
+					s.StartTransition(screen)
+					for i := 0; i < screenWidth*screenHeight; i++ {
+						s.SetPoint(screen, img, i, i)
+					}
+					applied++
+				})
+			}
+		}(f)
+	}
+
+	done := make(chan struct{})
+	var render sync.WaitGroup
+	render.Add(2)
+	go func() {
+		defer render.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			Update(func() {
+				for screen := range s.screens {
+					frame := s.ScreenFrame(screen)
+					for i := 4; i < len(frame); i++ {
+						if frame[i] != frame[i%4] {
+							t.Errorf("screen %d is drawn half updated", screen)
+							return
+						}
+					}
+				}
+			})
+		}
+	}()
+	go func() {
+		defer render.Done()
+		for r := 0; r < rounds; r++ {
+			Update(func() {
+				s.requests.CancelAll()
+			})
+			runtime.Gosched()
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	render.Wait()
+
+	if applied > fetchers*rounds {
+		t.Errorf("applied %d responses of %d", applied, fetchers*rounds)
+	}
+	for screen := range s.requests {
+		if s.requests[screen].cancel != nil {
+			t.Errorf("request for screen %d is left open", screen)
+		}
+	}
+}
+
+func TestScreenRequestsNewerWins(t *testing.T) {
+	requests := make(ScreenRequests, 1)
+	oldCtx, oldSeq := requests.Begin(0)
+	_, newSeq := requests.Begin(0)
+	if oldCtx.Err() == nil {
+		t.Error("older request is not cancelled")
+	}
+	if requests.Finish(0, oldSeq) {
+		t.Error("older response is applied")
+	}
+	if !requests.Finish(0, newSeq) {
+		t.Error("newer response is dropped")
+	}
+	if requests.Finish(0, newSeq) {
+		t.Error("response is applied twice")
+	}
+
+	ctx, seq := requests.Begin(0)
+	requests.CancelAll()
+	if ctx.Err() == nil || requests.Finish(0, seq) {
+		t.Error("cancelled request is applied")
+	}
+}
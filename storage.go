@@ -0,0 +1,483 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"github.com/hexops/vecty/prop"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall/js"
+	"time"
+)
+
+// Storage - хранилище настроек эмулятора: строки по ключам
+type Storage interface {
+	Get(key string) *string
+	Set(key string, data *[]byte) error //nil удаляет ключ
+	Keys() []string
+}
+
+const STORAGE_LOCAL = "local"
+const STORAGE_INDEXEDDB = "indexeddb"
+const STORAGE_MEMORY = "memory" //ничего не сохраняется между запусками
+
+var storage Storage = LocalStorage{}
+var storageKind = STORAGE_LOCAL
+
+// выбор хранилища всегда лежит в localStorage, иначе его не найти при запуске
+const storageKindKey = "storage"
+
+type LocalStorage struct{}
+
+func (LocalStorage) Get(key string) *string {
+	ls := js.Global().Get("localStorage").Call("getItem", key)
+	if ls.IsNull() || ls.IsUndefined() {
+		return nil
+	}
+	lss := ls.String()
+	return &lss
+}
+
+// Set возвращает ошибку, если запись не поместилась: setItem бросает QuotaExceededError
+func (LocalStorage) Set(key string, data *[]byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			jsErr, ok := r.(js.Error)
+			if !ok {
+				panic(r)
+			}
+			err = jsErr
+		}
+	}()
+	if data == nil {
+		js.Global().Get("localStorage").Call("removeItem", key)
+	} else {
+		js.Global().Get("localStorage").Call("setItem", key, string(*data))
+	}
+	return nil
+}
+
+func (LocalStorage) Keys() []string {
+	ls := js.Global().Get("localStorage")
+	var keys []string
+	for i := 0; i < ls.Get("length").Int(); i++ {
+		if key := ls.Call("key", i).String(); key != storageKindKey {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+type MemoryStorage struct {
+	data map[string]string
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{data: map[string]string{}}
+}
+
+func (s *MemoryStorage) Get(key string) *string {
+	if value, ok := s.data[key]; ok {
+		return &value
+	}
+	return nil
+}
+
+func (s *MemoryStorage) Set(key string, data *[]byte) error {
+	if data == nil {
+		delete(s.data, key)
+	} else {
+		s.data[key] = string(*data)
+	}
+	return nil
+}
+
+func (s *MemoryStorage) Keys() []string {
+	var keys []string
+	for key := range s.data {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// IndexedDBStorage читает все записи при открытии и дальше отвечает из памяти,
+// а изменения записывает в базу асинхронно
+type IndexedDBStorage struct {
+	MemoryStorage
+	db      js.Value
+	pending sync.WaitGroup //начатые и еще не завершенные транзакции записи
+}
+
+const indexedDBName = "aircube-emulator"
+const indexedDBStore = "settings"
+
+// wait ждет события success или error у запроса IndexedDB
+func wait(request js.Value) error {
+	return <-requestDone(request)
+}
+
+// requestDone подписывается на результат запроса сразу, пока управление не вернулось в браузер
+func requestDone(request js.Value) <-chan error {
+	return settled(request, "onsuccess", "onerror")
+}
+
+// transactionDone ждет, пока транзакция будет записана; при ошибке она прерывается (abort)
+func transactionDone(tx js.Value) <-chan error {
+	return settled(tx, "oncomplete", "onabort")
+}
+
+func settled(target js.Value, onSuccess string, onFailure string) <-chan error {
+	done := make(chan error, 1)
+	var success, failure js.Func
+	success = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		done <- nil
+		return nil
+	})
+	failure = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		err := target.Get("error")
+		if err.IsNull() || err.IsUndefined() {
+			done <- errors.New("aborted")
+		} else {
+			done <- errors.New(err.Call("toString").String())
+		}
+		return nil
+	})
+	target.Set(onSuccess, success)
+	target.Set(onFailure, failure)
+	result := make(chan error, 1)
+	go func() {
+		err := <-done
+		success.Release()
+		failure.Release()
+		result <- err
+	}()
+	return result
+}
+
+func OpenIndexedDB() (*IndexedDBStorage, error) {
+	idb := js.Global().Get("indexedDB")
+	if idb.IsUndefined() || idb.IsNull() {
+		return nil, errors.New("IndexedDB is not supported")
+	}
+	request := idb.Call("open", indexedDBName, 1)
+	upgrade := js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		request.Get("result").Call("createObjectStore", indexedDBStore)
+		return nil
+	})
+	request.Set("onupgradeneeded", upgrade)
+	err := wait(request)
+	upgrade.Release()
+	if err != nil {
+		return nil, err
+	}
+	s := &IndexedDBStorage{MemoryStorage: *NewMemoryStorage(), db: request.Get("result")}
+	store := s.db.Call("transaction", indexedDBStore, "readonly").Call("objectStore", indexedDBStore)
+	keys := store.Call("getAllKeys")
+	if err := wait(keys); err != nil {
+		return nil, err
+	}
+	values := store.Call("getAll")
+	if err := wait(values); err != nil {
+		return nil, err
+	}
+	for i := 0; i < keys.Get("result").Length(); i++ {
+		s.data[keys.Get("result").Index(i).String()] = values.Get("result").Index(i).String()
+	}
+	return s, nil
+}
+
+// Set записывает асинхронно, ошибки транзакции только выводятся в лог
+func (s *IndexedDBStorage) Set(key string, data *[]byte) error {
+	s.MemoryStorage.Set(key, data)
+	tx := s.db.Call("transaction", indexedDBStore, "readwrite")
+	store := tx.Call("objectStore", indexedDBStore)
+	if data == nil {
+		store.Call("delete", key)
+	} else {
+		store.Call("put", string(*data), key)
+	}
+	s.pending.Add(1)
+	done := transactionDone(tx)
+	go func() {
+		if err := <-done; err != nil {
+			println("IndexedDB write failed ", key, err.Error())
+		}
+		s.pending.Done()
+	}()
+	return nil
+}
+
+// Wait ждет окончания всех начатых записей. Блокирует, поэтому вызывается из отдельной горутины
+// без блокировки состояния: ответы IndexedDB приходят через обработчики событий браузера
+func (s *IndexedDBStorage) Wait() {
+	s.pending.Wait()
+}
+
+// ReloadAfterWrites перезагружает страницу, когда хранилища закончат запись:
+// перезагрузка прерывает незавершенные транзакции IndexedDB
+func ReloadAfterWrites(stores ...Storage) {
+	go func() {
+		for _, s := range stores {
+			if s, ok := s.(*IndexedDBStorage); ok {
+				s.Wait()
+			}
+		}
+		js.Global().Get("location").Call("reload")
+	}()
+}
+
+// CopyStorage переносит все ключи из одного хранилища в другое
+func CopyStorage(from Storage, to Storage) {
+	for _, key := range from.Keys() {
+		data := []byte(*from.Get(key))
+		if err := to.Set(key, &data); err != nil {
+			println("Can't copy setting ", key, err.Error())
+		}
+	}
+}
+
+// InitStorage выбирает хранилище: параметр страницы ?storage=, затем localStorage "storage".
+// Вызывается первым в main, до чтения любых настроек.
+func InitStorage() {
+	kind := STORAGE_LOCAL
+	if stored := (LocalStorage{}).Get(storageKindKey); stored != nil {
+		kind = *stored
+	}
+	param := js.Global().Get("URLSearchParams").New(js.Global().Get("location").Get("search")).Call("get", "storage")
+	if !param.IsNull() {
+		kind = param.String()
+	}
+	switch kind {
+	case STORAGE_MEMORY:
+		storage = NewMemoryStorage()
+		CopyStorage(LocalStorage{}, storage)
+	case STORAGE_INDEXEDDB:
+		s, err := OpenIndexedDB()
+		if err != nil {
+			println("IndexedDB failed, using localStorage ", err.Error())
+			kind = STORAGE_LOCAL
+			break
+		}
+		if len(s.Keys()) == 0 {
+			//первый запуск с IndexedDB - забираем настройки из localStorage
+			CopyStorage(LocalStorage{}, s)
+		}
+		storage = s
+	default:
+		kind = STORAGE_LOCAL
+	}
+	storageKind = kind
+	println("Storage is ", storageKind)
+	MigrateStorage(storage)
+}
+
+// SetStorageKind запоминает хранилище и перезагружает страницу; настройки переносятся в новое хранилище.
+// Вызывается из горутины: открытие IndexedDB ждет ответа браузера
+func SetStorageKind(kind string) {
+	var target *IndexedDBStorage
+	if kind == STORAGE_INDEXEDDB {
+		s, err := OpenIndexedDB()
+		if err != nil {
+			println("IndexedDB failed ", err.Error())
+			return
+		}
+		target = s
+	}
+	Update(func() {
+		if kind == storageKind {
+			return
+		}
+		written := []Storage{storage}
+		if target != nil {
+			for _, key := range target.Keys() {
+				target.Set(key, nil)
+			}
+			CopyStorage(storage, target)
+			written = append(written, target)
+		} else if storageKind == STORAGE_INDEXEDDB {
+			CopyStorage(storage, LocalStorage{})
+		}
+		data := []byte(kind)
+		(LocalStorage{}).Set(storageKindKey, &data)
+		ReloadAfterWrites(written...)
+	})
+}
+
+// версии схемы настроек
+// 1 - исходная: config {token, sn, powered_on}
+// 2 - в config добавлены version и backend, учетные записи "identities"
+const StorageSchema = 2
+
+const schemaKey = "schema"
+
+// migrations[v] переводит хранилище из версии v в v+1
+var migrations = map[int]func(s Storage){
+	1: migrateConfigurations,
+}
+
+func StoredSchema(s Storage) int {
+	version := 1
+	if stored := s.Get(schemaKey); stored != nil {
+		version, _ = strconv.Atoi(*stored)
+	}
+	return version
+}
+
+func MigrateStorage(s Storage) {
+	version := StoredSchema(s)
+	if version > StorageSchema {
+		println("Storage schema ", version, " is newer than supported ", StorageSchema)
+		return
+	}
+	for ; version < StorageSchema; version++ {
+		println("Migrate storage from schema ", version)
+		if migrate := migrations[version]; migrate != nil {
+			migrate(s)
+		}
+	}
+	data := []byte(strconv.Itoa(StorageSchema))
+	s.Set(schemaKey, &data)
+}
+
+// migrateConfigurations проставляет версию во все config; испорченные удаляются, куб заново пройдет привязку
+func migrateConfigurations(s Storage) {
+	for _, key := range s.Keys() {
+		if key != "config" && !strings.HasSuffix(key, ".config") {
+			continue
+		}
+		var config Configuration
+		if err := json.Unmarshal([]byte(*s.Get(key)), &config); err != nil {
+			println("Drop broken ", key)
+			s.Set(key, nil)
+			continue
+		}
+		config.Version = ConfigVersion
+		data, _ := json.Marshal(config)
+		s.Set(key, &data)
+	}
+}
+
+// EmulatorState - все настройки эмулятора для переноса на другую машину
+type EmulatorState struct {
+	Schema int               `json:"schema"`
+	Data   map[string]string `json:"data"`
+}
+
+func ExportState() []byte {
+	state := EmulatorState{Schema: StoredSchema(storage), Data: map[string]string{}}
+	for _, key := range storage.Keys() {
+		if key == schemaKey {
+			continue
+		}
+		state.Data[key] = *storage.Get(key)
+	}
+	data, _ := json.MarshalIndent(state, "", "  ")
+	return data
+}
+
+// ImportState заменяет все настройки импортированными и перезагружает страницу
+func ImportState(data []byte) error {
+	var state EmulatorState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	if state.Data == nil {
+		return errors.New("no emulator settings in the file")
+	}
+	if state.Schema > StorageSchema {
+		return errors.New("settings are from a newer emulator version")
+	}
+	imported := NewMemoryStorage()
+	for key, value := range state.Data {
+		imported.data[key] = value
+	}
+	schema := []byte(strconv.Itoa(state.Schema))
+	imported.Set(schemaKey, &schema)
+	MigrateStorage(imported)
+	for _, key := range storage.Keys() {
+		storage.Set(key, nil)
+	}
+	CopyStorage(imported, storage)
+	ReloadAfterWrites(storage)
+	return nil
+}
+
+func ExportToFile() {
+	Download(ExportState(), "application/json", "aircube-emulator-"+time.Now().Format("20060102-150405")+".json")
+}
+
+// ImportFromFile открывает выбор файла и импортирует настройки
+func ImportFromFile() {
+	input := js.Global().Get("document").Call("createElement", "input")
+	input.Set("type", "file")
+	input.Set("accept", "application/json,.json")
+	var onChange js.Func
+	onChange = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+		onChange.Release()
+		files := input.Get("files")
+		if files.Length() == 0 {
+			return nil
+		}
+		var onText js.Func
+		onText = js.FuncOf(func(this js.Value, args []js.Value) interface{} {
+			onText.Release()
+			var err error
+			Update(func() {
+				err = ImportState([]byte(args[0].String()))
+			})
+			if err != nil {
+				println("Import failed ", err.Error())
+				js.Global().Call("alert", "Import failed: "+err.Error())
+			}
+			return nil
+		})
+		files.Index(0).Call("text").Call("then", onText)
+		return nil
+	})
+	input.Call("addEventListener", "change", onChange)
+	input.Call("click")
+}
+
+type StoragePanel struct {
+	vecty.Core
+}
+
+func (p *StoragePanel) Render() vecty.ComponentOrHTML {
+	kinds := []string{STORAGE_LOCAL, STORAGE_INDEXEDDB, STORAGE_MEMORY}
+	var options vecty.List
+	for _, kind := range kinds {
+		options = append(options, elem.Option(vecty.Markup(
+			prop.Value(kind),
+			vecty.Property("selected", kind == storageKind),
+		), vecty.Text(kind)))
+	}
+	return elem.Span(vecty.Markup(vecty.Class("storage")),
+		elem.Select(vecty.Markup(
+			vecty.Property("title", "settings storage"),
+			On("change", func(e *vecty.Event) {
+				//открытие IndexedDB ждет ответа браузера, в обработчике события ждать нельзя
+				go SetStorageKind(e.Target.Get("value").String())
+			}),
+		), options),
+		elem.Span(vecty.Markup(
+			vecty.Class("fa-button"),
+			vecty.Property("title", "export settings"),
+			On("click", func(event *vecty.Event) {
+				ExportToFile()
+			}),
+		), vecty.Text("\uF019")),
+		elem.Span(vecty.Markup(
+			vecty.Class("fa-button"),
+			vecty.Property("title", "import settings"),
+			On("click", func(event *vecty.Event) {
+				ImportFromFile()
+			}),
+		), vecty.Text("\uF093")),
+	)
+}
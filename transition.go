@@ -0,0 +1,145 @@
+package main
+
+import (
+	"time"
+)
+
+const TRANSITION_SLIDE = "slide"
+const TRANSITION_FADE = "fade"
+const TRANSITION_WIPE = "wipe"
+
+const defaultTransitionDuration = 400 //ms
+
+type Transition struct {
+	kind     string
+	duration time.Duration
+	start    time.Time
+	from     []byte
+	mirrored bool
+}
+
+func NewTransition(kind string, duration *int) *Transition {
+	switch kind {
+	case TRANSITION_SLIDE, TRANSITION_FADE, TRANSITION_WIPE:
+	default:
+		return nil
+	}
+	ms := defaultTransitionDuration
+	if duration != nil && *duration > 0 {
+		ms = *duration
+	}
+	return &Transition{kind: kind, duration: time.Duration(ms) * time.Millisecond}
+}
+
+// QueueTransition запоминает переход, который будет проигран при получении нового содержимого экрана
+func (s *ScreenState) QueueTransition(screen int, kind string, duration *int) {
+	s.screens[screen].pending = NewTransition(kind, duration)
+}
+
+// StartTransition вызывается непосредственно перед перезаписью буфера экрана
+func (s *ScreenState) StartTransition(screen int) {
+	t := s.screens[screen].pending
+	s.screens[screen].pending = nil
+	if t == nil {
+		return
+	}
+	t.from = make([]byte, len(s.screens[screen].points))
+	copy(t.from, s.screens[screen].points)
+	t.start = time.Now()
+	//буфер хранится повернутым, если куб перевернут
+	t.mirrored = s.rotations[screen] == 180
+	s.screens[screen].transition = t
+}
+
+// BeginTransition проигрывает переход от черного экрана к текущему содержимому
+func (s *ScreenState) BeginTransition(screen int, kind string, duration *int) {
+	t := NewTransition(kind, duration)
+	if t == nil {
+		return
+	}
+	t.from = make([]byte, len(s.screens[screen].points))
+	for j := 0; j < screenWidth*screenHeight; j++ {
+		t.from[j*4+3] = 255
+	}
+	t.start = time.Now()
+	t.mirrored = s.rotations[screen] == 180
+	s.screens[screen].pending = nil
+	s.screens[screen].transition = t
+}
+
+// ScreenFrame возвращает буфер для отрисовки на canvas с учетом активного перехода
+func (s *ScreenState) ScreenFrame(screen int) []byte {
+	t := s.screens[screen].transition
+	if t == nil {
+		return s.screens[screen].points
+	}
+	progress := float64(time.Since(t.start)) / float64(t.duration)
+	if progress >= 1 {
+		s.screens[screen].transition = nil
+		return s.screens[screen].points
+	}
+	if s.screens[screen].frame == nil {
+		s.screens[screen].frame = make([]byte, len(s.screens[screen].points))
+	}
+	ComposeTransition(t, s.screens[screen].points, s.screens[screen].frame, progress)
+	return s.screens[screen].frame
+}
+
+func ComposeTransition(t *Transition, to []byte, out []byte, progress float64) {
+	switch t.kind {
+	case TRANSITION_SLIDE:
+		shift := int(progress * float64(screenWidth))
+		for y := 0; y < screenHeight; y++ {
+			for x := 0; x < screenWidth; x++ {
+				//новое изображение выезжает справа
+				vx := x
+				if t.mirrored {
+					vx = screenWidth - 1 - x
+				}
+				var src []byte
+				var sx int
+				if vx < screenWidth-shift {
+					src = t.from
+					sx = vx + shift
+				} else {
+					src = to
+					sx = vx - (screenWidth - shift)
+				}
+				if t.mirrored {
+					sx = screenWidth - 1 - sx
+				}
+				copy(out[(y*screenWidth+x)*4:(y*screenWidth+x)*4+4], src[(y*screenWidth+sx)*4:(y*screenWidth+sx)*4+4])
+			}
+		}
+	case TRANSITION_WIPE:
+		edge := int(progress * float64(screenWidth))
+		for y := 0; y < screenHeight; y++ {
+			for x := 0; x < screenWidth; x++ {
+				vx := x
+				if t.mirrored {
+					vx = screenWidth - 1 - x
+				}
+				src := t.from
+				if vx < edge {
+					src = to
+				}
+				pos := (y*screenWidth + x) * 4
+				copy(out[pos:pos+4], src[pos:pos+4])
+			}
+		}
+	case TRANSITION_FADE:
+		//сначала гасим старое изображение, затем проявляем новое
+		src := t.from
+		level := 1 - 2*progress
+		if progress >= 0.5 {
+			src = to
+			level = 2*progress - 1
+		}
+		for j := 0; j < screenWidth*screenHeight; j++ {
+			out[j*4] = byte(float64(src[j*4]) * level)
+			out[j*4+1] = byte(float64(src[j*4+1]) * level)
+			out[j*4+2] = byte(float64(src[j*4+2]) * level)
+			out[j*4+3] = 255
+		}
+	}
+}
@@ -0,0 +1,52 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"github.com/hexops/vecty"
+	"github.com/hexops/vecty/elem"
+	"math"
+	"strconv"
+)
+
+const view3DTilt = -15 //deg
+
+// CubeTransform поворачивает куб в соответствии с его положением на столе
+func (c *Cube) CubeTransform() string {
+	return "rotateX(" + strconv.Itoa(view3DTilt) + "deg) " + c.orientation.CSSMatrix()
+}
+
+// FaceTransform располагает экраны по боковым граням призмы (для 4 граней - куба)
+func FaceTransform(screen int) string {
+	apothem := float64(screenWidth) / 2 / math.Tan(math.Pi/float64(faceCount))
+	return "rotateY(" + strconv.FormatFloat(360*float64(screen)/float64(faceCount), 'f', 2, 64) + "deg) translateZ(" +
+		strconv.FormatFloat(apothem, 'f', 2, 64) + "px)"
+}
+
+func (c *Cube) Toggle3D() {
+	c.view3D = !c.view3D
+	state := []byte(strconv.FormatBool(c.view3D))
+	storage.Set(c.Key("view3d"), &state)
+	vecty.Rerender(emulator)
+	if c.display.LCD {
+		//масштаб экранов зависит от вида
+		c.ResizeCanvases()
+	}
+}
+
+type View3DButton struct {
+	vecty.Core
+	cube *Cube
+}
+
+func (p *View3DButton) Render() vecty.ComponentOrHTML {
+	c := p.cube
+	return elem.Span(vecty.Markup(
+		vecty.Class("view-button"),
+		vecty.MarkupIf(c.view3D, vecty.Class("on")),
+		On("click", func(event *vecty.Event) {
+			c.Toggle3D()
+		}),
+	), vecty.Text("\uF1B2"))
+}